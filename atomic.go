@@ -0,0 +1,39 @@
+package filic
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+)
+
+// atomicWriteFile writes a file by calling write with a temporary file in
+// the same directory as target, then renaming it over target. Readers see
+// either the old or the new content, never a partial write. The temporary
+// file is removed if anything fails.
+func atomicWriteFile(target string, perm fs.FileMode, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(path.Dir(target), "."+path.Base(target)+".*.tmp")
+	if err != nil {
+		return newPathError("write", target, err)
+	}
+
+	err = write(tmp)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), target)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return newPathError("write", target, err)
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+package filic
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"iter"
+	"os"
+	"path"
+	"sort"
+)
+
+// CAS is a content-addressable blob store rooted at a Directory. Blobs are
+// named by the hex encoded SHA-256 digest of their content and stored under
+// sharded paths, so the blob abcdef... lives at ab/cd/abcdef....
+//
+// Blobs are written to a temporary file and renamed into place once
+// complete, so concurrent writers never expose a partial blob, and storing
+// the same content twice keeps a single copy.
+type CAS struct {
+	Dir *Directory
+}
+
+// casTmpDir is the directory of a CAS holding blobs being written.
+const casTmpDir = "tmp"
+
+// NewCAS returns the blob store rooted at dir. The directory is created when
+// the first blob is stored.
+func NewCAS(dir *Directory) *CAS {
+	return &CAS{Dir: dir}
+}
+
+// validDigest reports whether digest is a hex encoded SHA-256 digest in
+// lowercase.
+func validDigest(digest string) bool {
+	return len(digest) == sha256.Size*2 && isLowerHex(digest)
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// blobPath returns the path of the blob with the given digest.
+func (c *CAS) blobPath(op, digest string) (string, error) {
+	if !validDigest(digest) {
+		return "", &PathError{Op: op, Path: c.Dir.Join(digest), Err: ErrInvalidDigest}
+	}
+	return c.Dir.Join(path.Join(digest[0:2], digest[2:4], digest)), nil
+}
+
+// File returns the File holding the blob with the given digest. The blob
+// should be treated as read-only.
+func (c *CAS) File(digest string) (*File, error) {
+	p, err := c.blobPath("open", digest)
+	if err != nil {
+		return nil, err
+	}
+	return NewFile(p), nil
+}
+
+// Put stores the content read from r and returns its digest.
+func (c *CAS) Put(r io.Reader) (string, error) {
+	tmpDir := c.Dir.Join(casTmpDir)
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return "", newPathError("put", c.Dir.Path, err)
+	}
+
+	tmp, err := os.CreateTemp(tmpDir, "blob-*")
+	if err != nil {
+		return "", newPathError("put", c.Dir.Path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, h), r)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", newPathError("put", c.Dir.Path, err)
+	}
+
+	digest := hex.EncodeToString(h.Sum(nil))
+	target, _ := c.blobPath("put", digest)
+
+	if _, err := os.Stat(target); err == nil {
+		return digest, nil
+	}
+
+	if err := os.Chmod(tmp.Name(), 0444); err != nil {
+		return "", newPathError("put", target, err)
+	}
+
+	// a concurrent Delete may remove the shard directories once they are
+	// empty, so retry if they vanish between creating them and the rename
+	for attempt := 0; ; attempt++ {
+		err = os.MkdirAll(path.Dir(target), 0755)
+		if err == nil {
+			err = os.Rename(tmp.Name(), target)
+		}
+		if err == nil || !errors.Is(err, os.ErrNotExist) || attempt == 2 {
+			break
+		}
+	}
+	if err != nil {
+		return "", newPathError("put", target, err)
+	}
+	return digest, nil
+}
+
+// Get opens the blob with the given digest. The content is verified while it
+// is read: a blob that was corrupted on disk makes the final Read return
+// ErrChecksumMismatch instead of io.EOF.
+func (c *CAS) Get(digest string) (io.ReadCloser, error) {
+	p, err := c.blobPath("get", digest)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(p)
+	if err != nil {
+		return nil, newPathError("get", p, err)
+	}
+	return &verifiedReader{file: file, hash: sha256.New(), digest: digest}, nil
+}
+
+// verifiedReader hashes a blob as it is read and checks the digest at EOF.
+type verifiedReader struct {
+	file   *os.File
+	hash   hash.Hash
+	digest string
+}
+
+func (r *verifiedReader) Read(p []byte) (int, error) {
+	n, err := r.file.Read(p)
+	r.hash.Write(p[:n])
+
+	if err == io.EOF && hex.EncodeToString(r.hash.Sum(nil)) != r.digest {
+		return n, &PathError{Op: "get", Path: r.file.Name(), Err: ErrChecksumMismatch}
+	}
+	if err != nil && err != io.EOF {
+		err = newPathError("get", r.file.Name(), err)
+	}
+	return n, err
+}
+
+func (r *verifiedReader) Close() error {
+	return r.file.Close()
+}
+
+// Verify reads the blob with the given digest and checks that its content
+// still matches the digest.
+func (c *CAS) Verify(digest string) error {
+	r, err := c.Get(digest)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	_, err = io.Copy(io.Discard, r)
+	return err
+}
+
+// Has reports whether the store holds a blob with the given digest.
+func (c *CAS) Has(digest string) bool {
+	p, err := c.blobPath("stat", digest)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(p)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// Delete removes the blob with the given digest. Deleting a blob that is not
+// in the store is not an error.
+func (c *CAS) Delete(digest string) error {
+	p, err := c.blobPath("delete", digest)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return newPathError("delete", p, err)
+	}
+
+	// drop shard directories left empty; failures just mean they aren't
+	os.Remove(path.Dir(p))
+	os.Remove(path.Dir(path.Dir(p)))
+	return nil
+}
+
+// Digests iterates over the digests of all the blobs in the store, in
+// lexical order.
+func (c *CAS) Digests() iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		shards, err := readShardNames(c.Dir.Path)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				yield("", newPathError("list", c.Dir.Path, err))
+			}
+			return
+		}
+
+		for _, first := range shards {
+			seconds, err := readShardNames(path.Join(c.Dir.Path, first))
+			if err != nil {
+				if !yield("", newPathError("list", c.Dir.Path, err)) {
+					return
+				}
+				continue
+			}
+
+			for _, second := range seconds {
+				dir := path.Join(c.Dir.Path, first, second)
+				entries, err := os.ReadDir(dir)
+				if err != nil {
+					if !yield("", newPathError("list", dir, err)) {
+						return
+					}
+					continue
+				}
+
+				for _, entry := range entries {
+					digest := entry.Name()
+					if !entry.Type().IsRegular() || !validDigest(digest) ||
+						digest[0:2] != first || digest[2:4] != second {
+						continue
+					}
+					if !yield(digest, nil) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// readShardNames returns the names of the two character shard directories
+// in dir, sorted.
+func readShardNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() && len(name) == 2 && isLowerHex(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// GCOptions configures CAS.GC.
+type GCOptions struct {
+	// References returns the digests of the blobs referenced by a blob,
+	// for stores holding manifests or trees pointing at other blobs. When
+	// nil, only the roots themselves are kept.
+	References func(digest string) ([]string, error)
+	// DryRun reports what would be removed without removing anything.
+	DryRun bool
+}
+
+// GCReport describes the outcome of CAS.GC.
+type GCReport struct {
+	// Removed holds the digests of the removed blobs, sorted.
+	Removed []string
+	// Bytes is the total size of the removed blobs.
+	Bytes int64
+}
+
+// GC removes every blob that is not reachable from the given root digests,
+// following references reported by opts.References. Blobs stored while GC
+// runs may be removed unless they are reachable from the roots, so callers
+// should not Put concurrently with a collection.
+func (c *CAS) GC(roots []string, opts GCOptions) (*GCReport, error) {
+	live := make(map[string]bool)
+	stack := append([]string(nil), roots...)
+
+	for len(stack) > 0 {
+		digest := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if live[digest] {
+			continue
+		}
+		live[digest] = true
+
+		if opts.References == nil || !c.Has(digest) {
+			continue
+		}
+		refs, err := opts.References(digest)
+		if err != nil {
+			return nil, newPathError("gc", c.Dir.Path, err)
+		}
+		stack = append(stack, refs...)
+	}
+
+	report := &GCReport{}
+	var errs []error
+
+	for digest, err := range c.Digests() {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if live[digest] {
+			continue
+		}
+
+		p, _ := c.blobPath("gc", digest)
+		if info, err := os.Stat(p); err == nil {
+			report.Bytes += info.Size()
+		}
+
+		if !opts.DryRun {
+			if err := c.Delete(digest); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		report.Removed = append(report.Removed, digest)
+	}
+
+	return report, errors.Join(errs...)
+}
@@ -0,0 +1,170 @@
+package filic_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func TestCASPutGet(t *testing.T) {
+	cleanup()
+
+	cas := filic.NewCAS(filic.NewDirectory(getTempDirPath()))
+
+	digest, err := cas.Put(strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if digest != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("Unexpected digest %v", digest)
+	}
+
+	file, _ := cas.File(digest)
+	if file.Path != getTempDirPath()+"/2c/f2/"+digest {
+		t.Errorf("Expected sharded path, got %v", file.Path)
+	}
+
+	again, _ := cas.Put(strings.NewReader("hello"))
+	if again != digest {
+		t.Errorf("Expected the same digest for the same content, got %v", again)
+	}
+
+	if !cas.Has(digest) {
+		t.Error("Expected the blob to be stored")
+	}
+
+	r, err := cas.Get(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	r.Close()
+	if err != nil || string(data) != "hello" {
+		t.Errorf("Expected %q, got %q (%v)", "hello", data, err)
+	}
+
+	if err := cas.Delete(digest); err != nil {
+		t.Error(err)
+	}
+	if cas.Has(digest) {
+		t.Error("Expected the blob to be deleted")
+	}
+
+	_, err = cas.Get(digest)
+	if !errors.Is(err, filic.ErrNotExist) {
+		t.Errorf("Expected ErrNotExist, got %v", err)
+	}
+
+	_, err = cas.Get("../../etc/passwd")
+	if !errors.Is(err, filic.ErrInvalidDigest) {
+		t.Errorf("Expected ErrInvalidDigest, got %v", err)
+	}
+
+	cleanup()
+}
+
+func TestCASVerify(t *testing.T) {
+	cleanup()
+
+	cas := filic.NewCAS(filic.NewDirectory(getTempDirPath()))
+	digest, _ := cas.Put(strings.NewReader("original"))
+
+	if err := cas.Verify(digest); err != nil {
+		t.Error(err)
+	}
+
+	file, _ := cas.File(digest)
+	os.Chmod(file.Path, 0644)
+	file.Write([]byte("tampered"))
+
+	if err := cas.Verify(digest); !errors.Is(err, filic.ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, got %v", err)
+	}
+
+	cleanup()
+}
+
+func TestCASConcurrentPut(t *testing.T) {
+	cleanup()
+
+	cas := filic.NewCAS(filic.NewDirectory(getTempDirPath()))
+	content := bytes.Repeat([]byte("artifact"), 10000)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cas.Put(bytes.NewReader(content)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var digests []string
+	for digest, err := range cas.Digests() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		digests = append(digests, digest)
+	}
+
+	if len(digests) != 1 {
+		t.Errorf("Expected a single stored blob, got %v", digests)
+	}
+
+	tmp, _ := os.ReadDir(getTempDirPath() + "/tmp")
+	if len(tmp) != 0 {
+		t.Errorf("Expected no temporary files left, got %v", len(tmp))
+	}
+
+	cleanup()
+}
+
+func TestCASGC(t *testing.T) {
+	cleanup()
+
+	cas := filic.NewCAS(filic.NewDirectory(getTempDirPath()))
+
+	leaf, _ := cas.Put(strings.NewReader("leaf"))
+	manifest, _ := cas.Put(strings.NewReader(leaf))
+	garbage, _ := cas.Put(strings.NewReader("garbage"))
+
+	refs := func(digest string) ([]string, error) {
+		if digest == manifest {
+			return []string{leaf}, nil
+		}
+		return nil, nil
+	}
+
+	report, err := cas.GC([]string{manifest}, filic.GCOptions{References: refs, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(report.Removed) != fmt.Sprint([]string{garbage}) || !cas.Has(garbage) {
+		t.Errorf("Expected dry run to report only %v, got %v", garbage, report.Removed)
+	}
+
+	report, err = cas.GC([]string{manifest}, filic.GCOptions{References: refs})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Bytes != int64(len("garbage")) {
+		t.Errorf("Expected %v bytes reclaimed, got %v", len("garbage"), report.Bytes)
+	}
+	if cas.Has(garbage) || !cas.Has(leaf) || !cas.Has(manifest) {
+		t.Error("Expected only unreachable blobs to be removed")
+	}
+
+	cleanup()
+}
@@ -0,0 +1,171 @@
+package filic
+
+import (
+	"context"
+	"os"
+	"path"
+	"time"
+)
+
+// CopyTo copies the contents and permission bits of the file to dest,
+// replacing any existing content. Parent directories of dest are created
+// if they don't exist. Copying a file onto itself, including through a
+// hard or symbolic link, fails with ErrSameFile.
+func (f *File) CopyTo(dest *File) error {
+	return f.CopyToContext(context.Background(), dest)
+}
+
+// CopyToContext is like CopyTo but stops and returns the context's error
+// once ctx is cancelled. A partially written dest is left in place.
+func (f *File) CopyToContext(ctx context.Context, dest *File) error {
+	src, err := os.Open(f.Path)
+	if err != nil {
+		return newPathError("copy", f.Path, err)
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return newPathError("copy", f.Path, err)
+	}
+
+	// opening dest would truncate the source before it is read
+	if destInfo, err := os.Stat(dest.Path); err == nil && os.SameFile(info, destInfo) {
+		return &PathError{Op: "copy", Path: dest.Path, Err: ErrSameFile}
+	}
+
+	parent := dest.OpenParent()
+	if err := parent.Create(); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dest.Path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return newPathError("copy", dest.Path, err)
+	}
+
+	if _, err := copyContext(ctx, out, src); err != nil {
+		out.Close()
+		return newPathError("copy", dest.Path, err)
+	}
+
+	if err := out.Close(); err != nil {
+		return newPathError("copy", dest.Path, err)
+	}
+
+	return newPathError("copy", dest.Path, os.Chmod(dest.Path, info.Mode().Perm()))
+}
+
+// CopyTo recursively copies the contents of the directory into dest, which
+// is created if it doesn't exist. Files keep their permission bits and
+// symbolic links are recreated rather than followed. Existing files in dest
+// are overwritten; other entries already in dest are left untouched.
+func (d *Directory) CopyTo(dest *Directory) error {
+	return d.CopyToContext(context.Background(), dest)
+}
+
+// CopyToContext is like CopyTo but stops and returns the context's error
+// once ctx is cancelled. Whatever was copied before the cancellation is
+// left in dest.
+func (d *Directory) CopyToContext(ctx context.Context, dest *Directory) error {
+	return d.CopyToWithOptions(ctx, dest, CopyOptions{})
+}
+
+// CopyOptions configures CopyToWithOptions.
+type CopyOptions struct {
+	// Ignore, if set, excludes matching entities from the copy.
+	Ignore *IgnoreMatcher
+	// PreserveTimes gives copied files and directories the modification
+	// time of their source.
+	PreserveTimes bool
+}
+
+// CopyToWithOptions is like CopyToContext but configured by opts.
+func (d *Directory) CopyToWithOptions(ctx context.Context, dest *Directory, opts CopyOptions) error {
+	if err := dest.Create(); err != nil {
+		return err
+	}
+
+	var dirs []*Entity
+	err := d.WalkWithOptions(ctx, WalkOptions{Ignore: opts.Ignore}, func(entity *Entity) error {
+		target := path.Join(dest.Path, relativePath(d.Path, entity.Path))
+		if err := copyEntity(ctx, entity, target); err != nil {
+			return err
+		}
+
+		if !opts.PreserveTimes {
+			return nil
+		}
+		if entity.entry.IsDir() {
+			dirs = append(dirs, entity)
+			return nil
+		}
+		return preserveModTime(entity, target)
+	})
+	if err != nil {
+		return err
+	}
+
+	// directory times change while their contents are copied, so they are
+	// set last, deepest first
+	for i := len(dirs) - 1; i >= 0; i-- {
+		target := path.Join(dest.Path, relativePath(d.Path, dirs[i].Path))
+		if err := preserveModTime(dirs[i], target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CopyToWithOptions is like CopyToContext but configured by opts. Only the
+// options that apply to a single file are used.
+func (f *File) CopyToWithOptions(ctx context.Context, dest *File, opts CopyOptions) error {
+	if err := f.CopyToContext(ctx, dest); err != nil {
+		return err
+	}
+	if opts.PreserveTimes {
+		return preserveModTime(&f.Entity, dest.Path)
+	}
+	return nil
+}
+
+// preserveModTime gives target the modification time of source. Symbolic
+// links are skipped, as their times can't be set portably.
+func preserveModTime(source *Entity, target string) error {
+	info, err := source.lstat()
+	if err != nil {
+		return newPathError("copy", source.Path, err)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return nil
+	}
+	return newPathError("copy", target, os.Chtimes(target, time.Time{}, info.ModTime()))
+}
+
+// copyEntity copies a single walked entity to target without descending
+// into directories, which the walk takes care of.
+func copyEntity(ctx context.Context, entity *Entity, target string) error {
+	info, err := entity.lstat()
+	if err != nil {
+		return newPathError("copy", entity.Path, err)
+	}
+
+	switch {
+	case info.IsDir():
+		return newPathError("copy", target, os.MkdirAll(target, info.Mode().Perm()))
+
+	case info.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(entity.Path)
+		if err != nil {
+			return newPathError("copy", entity.Path, err)
+		}
+		os.Remove(target)
+		return newPathError("copy", target, os.Symlink(link, target))
+
+	case info.Mode().IsRegular():
+		return NewFile(entity.Path).CopyToContext(ctx, NewFile(target))
+	}
+
+	// sockets, devices and named pipes are not copied
+	return nil
+}
@@ -0,0 +1,157 @@
+package filic_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func TestFileCopyTo(t *testing.T) {
+	cleanup()
+
+	src := filic.NewFile(path.Join(getTempDirPath(), "src.txt"))
+	src.Create()
+	src.Write([]byte("copy me"))
+	os.Chmod(src.Path, 0600)
+
+	dest := filic.NewFile(path.Join(getTempDirPath(), "nested", "dest.txt"))
+
+	err := src.CopyTo(dest)
+	if err != nil {
+		t.Error(err)
+	}
+
+	content, err := dest.ReadString()
+	if err != nil {
+		t.Error(err)
+	}
+	if content != "copy me" {
+		t.Errorf("Expected %q, got %q", "copy me", content)
+	}
+
+	info, err := os.Stat(dest.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	cleanup()
+}
+
+func TestFileCopyToSameFile(t *testing.T) {
+	cleanup()
+
+	src := filic.NewFile(path.Join(getTempDirPath(), "src.txt"))
+	src.Create()
+	src.Write([]byte("keep me"))
+	os.Link(src.Path, path.Join(getTempDirPath(), "link.txt"))
+	os.Symlink("src.txt", path.Join(getTempDirPath(), "symlink.txt"))
+
+	for _, name := range []string{"src.txt", "link.txt", "symlink.txt"} {
+		err := src.CopyTo(filic.NewFile(path.Join(getTempDirPath(), name)))
+		if !errors.Is(err, filic.ErrSameFile) {
+			t.Errorf("Expected ErrSameFile copying to %v, got %v", name, err)
+		}
+	}
+
+	content, _ := src.ReadString()
+	if content != "keep me" {
+		t.Errorf("Expected the source to be left alone, got %q", content)
+	}
+
+	cleanup()
+}
+
+func TestDirectoryCopyTo(t *testing.T) {
+	cleanup()
+
+	createTree(t, map[string]string{
+		"src/a.txt":     "a",
+		"src/sub/b.txt": "b",
+	})
+
+	src := filic.NewDirectory(path.Join(getTempDirPath(), "src"))
+	os.Symlink("a.txt", src.Join("link"))
+
+	dest := filic.NewDirectory(path.Join(getTempDirPath(), "dest"))
+
+	err := src.CopyTo(dest)
+	if err != nil {
+		t.Error(err)
+	}
+
+	for name, expected := range map[string]string{"a.txt": "a", "sub/b.txt": "b"} {
+		content, err := filic.NewFile(dest.Join(name)).ReadString()
+		if err != nil {
+			t.Error(err)
+		}
+		if content != expected {
+			t.Errorf("Expected %q in %v, got %q", expected, name, content)
+		}
+	}
+
+	link, err := os.Readlink(dest.Join("link"))
+	if err != nil {
+		t.Error(err)
+	}
+	if link != "a.txt" {
+		t.Errorf("Expected link to a.txt, got %v", link)
+	}
+
+	cleanup()
+}
+
+func TestDirectoryCopyToContextCancelled(t *testing.T) {
+	cleanup()
+
+	createTree(t, map[string]string{"src/a.txt": "a"})
+	src := filic.NewDirectory(path.Join(getTempDirPath(), "src"))
+	dest := filic.NewDirectory(path.Join(getTempDirPath(), "dest"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := src.CopyToContext(ctx, dest)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	cleanup()
+}
+
+func TestCopyPreserveTimes(t *testing.T) {
+	cleanup()
+
+	createTree(t, map[string]string{"src/sub/a.txt": "a"})
+
+	src := filic.NewDirectory(path.Join(getTempDirPath(), "src"))
+	dest := filic.NewDirectory(path.Join(getTempDirPath(), "dest"))
+
+	stamp := time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+	os.Chtimes(src.Join("sub/a.txt"), stamp, stamp)
+	os.Chtimes(src.Join("sub"), stamp, stamp)
+
+	err := src.CopyToWithOptions(context.Background(), dest, filic.CopyOptions{PreserveTimes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"sub", "sub/a.txt"} {
+		modTime, err := filic.NewEntity(dest.Join(name)).ModTime()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !modTime.Equal(stamp) {
+			t.Errorf("Expected %v to have modification time %v, got %v", name, stamp, modTime)
+		}
+	}
+
+	cleanup()
+}
@@ -0,0 +1,282 @@
+package filic
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+)
+
+// partialHashLen is how much of a file is hashed to tell apart files of the
+// same size before hashing them completely.
+const partialHashLen = 4096
+
+// DedupeMode selects what FindDuplicates does with the duplicates it finds.
+type DedupeMode int
+
+const (
+	// DedupeReport only reports duplicates.
+	DedupeReport DedupeMode = iota
+	// DedupeHardlink replaces duplicates with hard links to the first file
+	// of their group. The files then share their content, modes and
+	// times, so changing one changes all of them.
+	DedupeHardlink
+	// DedupeReflink replaces duplicates with copy-on-write clones of the
+	// first file of their group, which share storage but stay independent
+	// files. It needs a file system supporting reflinks, such as Btrfs or
+	// XFS on Linux.
+	DedupeReflink
+)
+
+// DuplicateOptions configures FindDuplicates.
+type DuplicateOptions struct {
+	// MinSize skips files smaller than this many bytes. Empty files are
+	// always skipped.
+	MinSize int64
+	// Ignore, if set, excludes matching entities from the search.
+	Ignore *IgnoreMatcher
+	// Replace selects what to do with the duplicates found.
+	Replace DedupeMode
+	// Concurrency is the maximum number of files hashed at the same time.
+	// Zero or a negative value uses runtime.GOMAXPROCS(0).
+	Concurrency int
+}
+
+// DuplicateGroup is a set of files with identical content. Files are sorted
+// by path; the first one is the one kept when replacing duplicates.
+type DuplicateGroup struct {
+	Size   int64
+	Digest string
+	Files  []*File
+}
+
+// DuplicateReport describes the outcome of FindDuplicates.
+type DuplicateReport struct {
+	// Groups holds the sets of identical files, largest files first.
+	Groups []DuplicateGroup
+	// Reclaimable is the space that replacing every duplicate would free.
+	Reclaimable int64
+	// Reclaimed is the space freed by replacing duplicates.
+	Reclaimed int64
+}
+
+// FindDuplicates finds the files below d with identical content. Files are
+// first grouped by size, then by a hash of their first bytes, and only
+// files still matching are hashed completely. Symbolic links are skipped,
+// and files that are already hard links of each other are reported once.
+func (d *Directory) FindDuplicates(opts DuplicateOptions) (*DuplicateReport, error) {
+	return d.FindDuplicatesContext(context.Background(), opts)
+}
+
+// FindDuplicatesContext is like FindDuplicates but stops and returns the
+// context's error once ctx is cancelled.
+func (d *Directory) FindDuplicatesContext(ctx context.Context, opts DuplicateOptions) (*DuplicateReport, error) {
+	candidates, err := d.sizeBuckets(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := ParallelOptions{Concurrency: opts.Concurrency}.concurrency()
+
+	// narrow down by partial, then full hash
+	candidates, err = refineDuplicates(ctx, workers, candidates, func(c *dupCandidate) (string, error) {
+		return hashPrefix(c.path, partialHashLen)
+	})
+	if err != nil {
+		return nil, err
+	}
+	candidates, err = refineDuplicates(ctx, workers, candidates, func(c *dupCandidate) (string, error) {
+		if c.info.Size() <= partialHashLen {
+			return c.digest, nil
+		}
+		return NewFile(c.path).HashContext(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DuplicateReport{}
+	for _, group := range candidates {
+		g := DuplicateGroup{Size: group[0].info.Size(), Digest: group[0].digest}
+		for _, c := range group {
+			g.Files = append(g.Files, NewFile(c.path))
+		}
+		report.Groups = append(report.Groups, g)
+		report.Reclaimable += g.Size * int64(len(g.Files)-1)
+	}
+
+	sort.Slice(report.Groups, func(i, j int) bool {
+		gi, gj := report.Groups[i], report.Groups[j]
+		if gi.Size != gj.Size {
+			return gi.Size > gj.Size
+		}
+		return gi.Files[0].Path < gj.Files[0].Path
+	})
+
+	if opts.Replace == DedupeReport {
+		return report, nil
+	}
+
+	var errs []error
+	for _, group := range candidates {
+		for _, c := range group[1:] {
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
+			if err := replaceDuplicate(group[0], c, opts.Replace); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			report.Reclaimed += c.info.Size()
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+// dupCandidate is a file that may have duplicates.
+type dupCandidate struct {
+	path   string
+	info   fs.FileInfo
+	digest string
+}
+
+// sizeBuckets walks the tree and groups regular files by size, dropping
+// sizes held by a single file.
+func (d *Directory) sizeBuckets(ctx context.Context, opts DuplicateOptions) ([][]*dupCandidate, error) {
+	bySize := make(map[int64][]*dupCandidate)
+	inodes := inodeSet{}
+
+	err := d.WalkWithOptions(ctx, WalkOptions{Ignore: opts.Ignore}, func(entity *Entity) error {
+		if entity.entry != nil && !entity.entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entity.Info()
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || info.Size() == 0 || info.Size() < opts.MinSize {
+			return nil
+		}
+		if inodes.seen(info) {
+			return nil
+		}
+
+		bySize[info.Size()] = append(bySize[info.Size()], &dupCandidate{path: entity.Path, info: info})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buckets [][]*dupCandidate
+	for _, bucket := range bySize {
+		if len(bucket) > 1 {
+			buckets = append(buckets, bucket)
+		}
+	}
+	return buckets, nil
+}
+
+// refineDuplicates hashes every candidate with hash and splits the groups by
+// the result, dropping candidates left without a match. Groups keep their
+// files sorted by path.
+func refineDuplicates(ctx context.Context, workers int, groups [][]*dupCandidate, hash func(*dupCandidate) (string, error)) ([][]*dupCandidate, error) {
+	var all []*dupCandidate
+	for _, group := range groups {
+		all = append(all, group...)
+	}
+
+	err := forEachParallel(ctx, workers, len(all), func(i int) error {
+		digest, err := hash(all[i])
+		all[i].digest = digest
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var refined [][]*dupCandidate
+	for _, group := range groups {
+		byDigest := make(map[string][]*dupCandidate)
+		for _, c := range group {
+			byDigest[c.digest] = append(byDigest[c.digest], c)
+		}
+		for _, matching := range byDigest {
+			if len(matching) > 1 {
+				sort.Slice(matching, func(i, j int) bool { return matching[i].path < matching[j].path })
+				refined = append(refined, matching)
+			}
+		}
+	}
+	return refined, nil
+}
+
+// hashPrefix returns the hex encoded SHA-256 digest of the first n bytes of
+// the file at p.
+func hashPrefix(p string, n int64) (string, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return "", newPathError("hash", p, err)
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.CopyN(h, file, n); err != nil && err != io.EOF {
+		return "", newPathError("hash", p, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// replaceDuplicate replaces the duplicate c of the file original with a
+// hard link or reflink to it. Nothing is replaced if either file changed
+// since it was hashed.
+func replaceDuplicate(original, c *dupCandidate, mode DedupeMode) error {
+	info, err := unchangedSince(c)
+	if err != nil {
+		return err
+	}
+	if _, err := unchangedSince(original); err != nil {
+		return err
+	}
+
+	tmp := path.Join(path.Dir(c.path), "."+path.Base(c.path)+".dedupe.tmp")
+	os.Remove(tmp)
+
+	switch mode {
+	case DedupeHardlink:
+		err = os.Link(original.path, tmp)
+	case DedupeReflink:
+		err = reflinkFile(original.path, tmp, info)
+	}
+	if err == nil {
+		// the original may have changed while it was linked or cloned
+		if _, err = unchangedSince(original); err == nil {
+			err = os.Rename(tmp, c.path)
+		}
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return newPathError("dedupe", c.path, err)
+	}
+	return nil
+}
+
+// unchangedSince stats the candidate again and reports an error if it is
+// no longer the same file, with the same size and modification time, as
+// when it was hashed.
+func unchangedSince(c *dupCandidate) (fs.FileInfo, error) {
+	info, err := os.Lstat(c.path)
+	if err != nil {
+		return nil, newPathError("dedupe", c.path, err)
+	}
+	if !os.SameFile(info, c.info) || info.Size() != c.info.Size() || !info.ModTime().Equal(c.info.ModTime()) {
+		return nil, &PathError{Op: "dedupe", Path: c.path, Err: errors.New("file changed since it was hashed")}
+	}
+	return info, nil
+}
@@ -0,0 +1,137 @@
+package filic_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func groupPaths(dir *filic.Directory, group filic.DuplicateGroup) []string {
+	var names []string
+	for _, f := range group.Files {
+		names = append(names, strings.TrimPrefix(f.Path, dir.Path+"/"))
+	}
+	return names
+}
+
+func TestFindDuplicates(t *testing.T) {
+	cleanup()
+
+	big := strings.Repeat("x", 10000)
+	dir := createTree(t, map[string]string{
+		"a.txt":           "same",
+		"b/a-copy.txt":    "same",
+		"c/deep/a.bak":    "same",
+		"other.txt":       "diff",
+		"big1.bin":        big + "1",
+		"big2.bin":        big + "2",
+		"big3.bin":        big + "1",
+		"empty1":          "",
+		"empty2":          "",
+		"unique-size.txt": "unique content",
+	})
+
+	report, err := dir.FindDuplicates(filic.DuplicateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %v", len(report.Groups))
+	}
+
+	expectNames(t, []string{"big1.bin", "big3.bin"}, groupPaths(dir, report.Groups[0]))
+	expectNames(t, []string{"a.txt", "b/a-copy.txt", "c/deep/a.bak"}, groupPaths(dir, report.Groups[1]))
+
+	if report.Reclaimable != 10001+2*4 {
+		t.Errorf("Expected %v reclaimable bytes, got %v", 10001+2*4, report.Reclaimable)
+	}
+	if report.Reclaimed != 0 {
+		t.Errorf("Expected nothing reclaimed without replacing, got %v", report.Reclaimed)
+	}
+
+	report, _ = dir.FindDuplicates(filic.DuplicateOptions{MinSize: 100})
+	if len(report.Groups) != 1 {
+		t.Errorf("Expected MinSize to skip small files, got %v groups", len(report.Groups))
+	}
+
+	cleanup()
+}
+
+func TestFindDuplicatesHardlink(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		"a.txt": "content",
+		"b.txt": "content",
+		"sub/c": "content",
+		"d.txt": "unrelated",
+	})
+
+	report, err := dir.FindDuplicates(filic.DuplicateOptions{Replace: filic.DedupeHardlink})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Reclaimed != 2*int64(len("content")) {
+		t.Errorf("Expected %v reclaimed bytes, got %v", 2*len("content"), report.Reclaimed)
+	}
+
+	a, _ := os.Stat(dir.Join("a.txt"))
+	for _, name := range []string{"b.txt", "sub/c"} {
+		info, err := os.Stat(dir.Join(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !os.SameFile(a, info) {
+			t.Errorf("Expected %v to be a hard link to a.txt", name)
+		}
+	}
+
+	// files already linked together are no longer duplicates
+	report, _ = dir.FindDuplicates(filic.DuplicateOptions{})
+	if len(report.Groups) != 0 {
+		t.Errorf("Expected no duplicates after linking, got %v", len(report.Groups))
+	}
+
+	cleanup()
+}
+
+func TestFindDuplicatesReflink(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		"a.txt": "content",
+		"b.txt": "content",
+	})
+
+	_, err := dir.FindDuplicates(filic.DuplicateOptions{Replace: filic.DedupeReflink})
+	if err != nil {
+		t.Skipf("Reflinks not supported here: %v", err)
+	}
+
+	content, _ := filic.NewFile(dir.Join("b.txt")).ReadString()
+	if content != "content" {
+		t.Errorf("Expected reflinked file to keep its content, got %q", content)
+	}
+
+	a, _ := os.Stat(dir.Join("a.txt"))
+	b, _ := os.Stat(dir.Join("b.txt"))
+	if os.SameFile(a, b) {
+		t.Error("Expected a reflink to be a separate file")
+	}
+
+	cleanup()
+}
+
+func TestFindDuplicatesNotExist(t *testing.T) {
+	cleanup()
+
+	_, err := filic.NewDirectory(getTempDirPath()).FindDuplicates(filic.DuplicateOptions{})
+	if !errors.Is(err, filic.ErrNotExist) {
+		t.Errorf("Expected ErrNotExist, got %v", err)
+	}
+}
@@ -0,0 +1,490 @@
+package filic
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// DefaultBlockSize is the block size used by Signature when none is given.
+const DefaultBlockSize = 2048
+
+// maxBlockSize bounds the block size of signatures and deltas, so that a
+// corrupt or hostile one can't make us allocate huge blocks.
+const maxBlockSize = 64 << 20
+
+// maxLiteralLen bounds the amount of literal data held in a single DeltaOp.
+const maxLiteralLen = 64 << 10
+
+// BlockSignature holds the checksums of one block of a file: a cheap rolling
+// checksum used to find candidate matches and a SHA-256 digest to confirm
+// them.
+type BlockSignature struct {
+	Weak   uint32
+	Strong [sha256.Size]byte
+}
+
+// Signature describes the blocks of a file, so that a delta against it can
+// be computed without access to the file itself.
+type Signature struct {
+	BlockSize int
+	Size      int64
+	Blocks    []BlockSignature
+}
+
+// DeltaOpKind tells whether a DeltaOp reuses blocks or carries new data.
+type DeltaOpKind byte
+
+const (
+	// DeltaCopy copies Count blocks starting at Block from the old file.
+	DeltaCopy DeltaOpKind = iota + 1
+	// DeltaData inserts the literal Data.
+	DeltaData
+)
+
+// DeltaOp is a single instruction of a Delta.
+type DeltaOp struct {
+	Kind  DeltaOpKind
+	Block int
+	Count int
+	Data  []byte
+}
+
+// Delta is the list of instructions rebuilding a new file from the blocks of
+// an old one, together with the digest of the new file to verify the result.
+type Delta struct {
+	BlockSize int
+	Digest    [sha256.Size]byte
+	Ops       []DeltaOp
+}
+
+// rollingChecksum is the weak checksum used by rsync. It can be updated in
+// constant time when its window slides by one byte.
+type rollingChecksum struct {
+	a, b uint32
+	n    uint32
+}
+
+func newRollingChecksum(block []byte) rollingChecksum {
+	var r rollingChecksum
+	r.n = uint32(len(block))
+	for i, c := range block {
+		r.a += uint32(c)
+		r.b += uint32(len(block)-i) * uint32(c)
+	}
+	return r
+}
+
+func (r *rollingChecksum) roll(out, in byte) {
+	r.a += uint32(in) - uint32(out)
+	r.b += r.a - r.n*uint32(out)
+}
+
+// rollOut removes the first byte from the window, shrinking it by one.
+func (r *rollingChecksum) rollOut(out byte) {
+	r.a -= uint32(out)
+	r.b -= r.n * uint32(out)
+	r.n--
+}
+
+func (r rollingChecksum) sum() uint32 {
+	return r.a&0xffff | r.b<<16
+}
+
+// Signature computes the signature of the file with the given block size,
+// which is typically done on the side holding the outdated copy. A block
+// size of zero or less uses DefaultBlockSize.
+func (f *File) Signature(blockSize int) (*Signature, error) {
+	if blockSize <= 0 {
+		blockSize = DefaultBlockSize
+	}
+	if blockSize > maxBlockSize {
+		return nil, &PathError{Op: "signature", Path: f.Path, Err: fmt.Errorf("block size %d too large", blockSize)}
+	}
+
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, newPathError("signature", f.Path, err)
+	}
+	defer file.Close()
+
+	sig := &Signature{BlockSize: blockSize}
+	block := make([]byte, blockSize)
+	for {
+		n, err := io.ReadFull(file, block)
+		if n > 0 {
+			sig.Size += int64(n)
+			sig.Blocks = append(sig.Blocks, BlockSignature{
+				Weak:   newRollingChecksum(block[:n]).sum(),
+				Strong: sha256.Sum256(block[:n]),
+			})
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return nil, newPathError("signature", f.Path, err)
+		}
+	}
+
+	return sig, nil
+}
+
+// validBlockSize reports whether n is a usable block size.
+func validBlockSize(n int) bool {
+	return n > 0 && n <= maxBlockSize
+}
+
+// validate checks that the signature is consistent: a usable block size and
+// exactly as many blocks as its size needs.
+func (s *Signature) validate() error {
+	if !validBlockSize(s.BlockSize) {
+		return fmt.Errorf("invalid signature: block size %d", s.BlockSize)
+	}
+	if s.Size < 0 {
+		return fmt.Errorf("invalid signature: size %d", s.Size)
+	}
+	blocks := (s.Size + int64(s.BlockSize) - 1) / int64(s.BlockSize)
+	if blocks != int64(len(s.Blocks)) {
+		return fmt.Errorf("invalid signature: %d blocks for a size of %d", len(s.Blocks), s.Size)
+	}
+	return nil
+}
+
+// blockLen returns the length of block i of the signed file.
+func (s *Signature) blockLen(i int) int {
+	return int(min(int64(s.BlockSize), s.Size-int64(i)*int64(s.BlockSize)))
+}
+
+// Delta computes the instructions turning the file described by sig into
+// this file. Only the data of blocks not found in sig ends up in the delta.
+// The file is streamed, so it doesn't need to fit in memory.
+func (f *File) Delta(sig *Signature) (*Delta, error) {
+	if err := sig.validate(); err != nil {
+		return nil, &PathError{Op: "delta", Path: f.Path, Err: err}
+	}
+
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, newPathError("delta", f.Path, err)
+	}
+	defer file.Close()
+
+	blocks := make(map[uint32][]int, len(sig.Blocks))
+	for i, block := range sig.Blocks {
+		blocks[block.Weak] = append(blocks[block.Weak], i)
+	}
+
+	delta := &Delta{BlockSize: sig.BlockSize}
+	digest := sha256.New()
+	r := bufio.NewReaderSize(io.TeeReader(file, digest), 64<<10)
+
+	var literal []byte
+	flush := func() {
+		if len(literal) > 0 {
+			delta.addData(literal)
+			literal = nil
+		}
+	}
+
+	// readBlock reads up to a block size into a new window
+	readBlock := func() ([]byte, error) {
+		window := make([]byte, sig.BlockSize)
+		n, err := io.ReadFull(r, window)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			err = nil
+		}
+		return window[:n], err
+	}
+
+	window, err := readBlock()
+	if err != nil {
+		return nil, newPathError("delta", f.Path, err)
+	}
+	weak := newRollingChecksum(window)
+
+	for len(window) > 0 {
+		if match := sig.find(blocks, weak.sum(), window); match >= 0 {
+			flush()
+			delta.addCopy(match)
+
+			window, err = readBlock()
+			if err != nil {
+				return nil, newPathError("delta", f.Path, err)
+			}
+			weak = newRollingChecksum(window)
+			continue
+		}
+
+		literal = append(literal, window[0])
+		if len(literal) >= maxLiteralLen {
+			flush()
+		}
+
+		c, err := r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			// near the end the window shrinks instead of sliding
+			weak.rollOut(window[0])
+			window = window[1:]
+			continue
+		}
+		if err != nil {
+			return nil, newPathError("delta", f.Path, err)
+		}
+
+		weak.roll(window[0], c)
+		window = append(window[1:], c)
+	}
+	flush()
+
+	copy(delta.Digest[:], digest.Sum(nil))
+	return delta, nil
+}
+
+// find returns the index of a block of the signature matching window, or
+// -1 if there is none.
+func (s *Signature) find(blocks map[uint32][]int, weak uint32, window []byte) int {
+	candidates, ok := blocks[weak]
+	if !ok {
+		return -1
+	}
+
+	strong := sha256.Sum256(window)
+	for _, i := range candidates {
+		if s.blockLen(i) == len(window) && s.Blocks[i].Strong == strong {
+			return i
+		}
+	}
+	return -1
+}
+
+// addCopy appends a copy of block, extending the last op when it copies the
+// preceding block.
+func (d *Delta) addCopy(block int) {
+	if n := len(d.Ops); n > 0 {
+		last := &d.Ops[n-1]
+		if last.Kind == DeltaCopy && last.Block+last.Count == block {
+			last.Count++
+			return
+		}
+	}
+	d.Ops = append(d.Ops, DeltaOp{Kind: DeltaCopy, Block: block, Count: 1})
+}
+
+func (d *Delta) addData(data []byte) {
+	d.Ops = append(d.Ops, DeltaOp{Kind: DeltaData, Data: data})
+}
+
+// Patch applies delta, computed against the signature of this file, to
+// bring the file up to date. The result is verified against the digest in
+// the delta before it atomically replaces the file; on mismatch the file is
+// left unchanged and an error matching ErrChecksumMismatch is returned.
+func (f *File) Patch(delta *Delta) error {
+	if !validBlockSize(delta.BlockSize) {
+		return &PathError{Op: "patch", Path: f.Path, Err: fmt.Errorf("invalid delta: block size %d", delta.BlockSize)}
+	}
+
+	old, err := os.Open(f.Path)
+	if err != nil {
+		return newPathError("patch", f.Path, err)
+	}
+	defer old.Close()
+
+	info, err := old.Stat()
+	if err != nil {
+		return newPathError("patch", f.Path, err)
+	}
+
+	return atomicWriteFile(f.Path, info.Mode().Perm(), func(w io.Writer) error {
+		digest := sha256.New()
+		w = io.MultiWriter(w, digest)
+
+		for _, op := range delta.Ops {
+			switch op.Kind {
+			case DeltaCopy:
+				blocks := (info.Size() + int64(delta.BlockSize) - 1) / int64(delta.BlockSize)
+				if op.Block < 0 || op.Count <= 0 || int64(op.Block) >= blocks || int64(op.Count) > blocks-int64(op.Block) {
+					return fmt.Errorf("delta copies blocks %d-%d beyond the end of the file", op.Block, op.Block+op.Count-1)
+				}
+				offset := int64(op.Block) * int64(delta.BlockSize)
+				length := int64(op.Count) * int64(delta.BlockSize)
+				if _, err := io.Copy(w, io.NewSectionReader(old, offset, length)); err != nil {
+					return err
+				}
+
+			case DeltaData:
+				if _, err := w.Write(op.Data); err != nil {
+					return err
+				}
+
+			default:
+				return fmt.Errorf("unknown delta operation %d", op.Kind)
+			}
+		}
+
+		if !bytes.Equal(digest.Sum(nil), delta.Digest[:]) {
+			return ErrChecksumMismatch
+		}
+		return nil
+	})
+}
+
+var (
+	signatureMagic = []byte("FSIG\x01")
+	deltaMagic     = []byte("FDLT\x01")
+)
+
+// MarshalBinary encodes the signature in a compact binary form.
+func (s *Signature) MarshalBinary() ([]byte, error) {
+	buf := bytes.NewBuffer(append([]byte(nil), signatureMagic...))
+	buf.Write(binary.AppendUvarint(nil, uint64(s.BlockSize)))
+	buf.Write(binary.AppendUvarint(nil, uint64(s.Size)))
+	buf.Write(binary.AppendUvarint(nil, uint64(len(s.Blocks))))
+	for _, block := range s.Blocks {
+		buf.Write(binary.LittleEndian.AppendUint32(nil, block.Weak))
+		buf.Write(block.Strong[:])
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a signature encoded by MarshalBinary.
+func (s *Signature) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	if err := readMagic(r, signatureMagic); err != nil {
+		return err
+	}
+
+	blockSize, err := binary.ReadUvarint(r)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	if blockSize > maxBlockSize || size > math.MaxInt64 {
+		return errors.New("invalid signature: block size or size out of range")
+	}
+	if count > uint64(r.Len())/(4+sha256.Size) {
+		return errors.New("invalid signature: truncated block list")
+	}
+
+	s.BlockSize, s.Size = int(blockSize), int64(size)
+	s.Blocks = make([]BlockSignature, count)
+	for i := range s.Blocks {
+		var raw [4]byte
+		r.Read(raw[:])
+		s.Blocks[i].Weak = binary.LittleEndian.Uint32(raw[:])
+		r.Read(s.Blocks[i].Strong[:])
+	}
+	return s.validate()
+}
+
+// MarshalBinary encodes the delta in a compact binary form.
+func (d *Delta) MarshalBinary() ([]byte, error) {
+	buf := bytes.NewBuffer(append([]byte(nil), deltaMagic...))
+	buf.Write(binary.AppendUvarint(nil, uint64(d.BlockSize)))
+	buf.Write(d.Digest[:])
+	buf.Write(binary.AppendUvarint(nil, uint64(len(d.Ops))))
+	for _, op := range d.Ops {
+		buf.WriteByte(byte(op.Kind))
+		switch op.Kind {
+		case DeltaCopy:
+			buf.Write(binary.AppendUvarint(nil, uint64(op.Block)))
+			buf.Write(binary.AppendUvarint(nil, uint64(op.Count)))
+		case DeltaData:
+			buf.Write(binary.AppendUvarint(nil, uint64(len(op.Data))))
+			buf.Write(op.Data)
+		default:
+			return nil, fmt.Errorf("unknown delta operation %d", op.Kind)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a delta encoded by MarshalBinary.
+func (d *Delta) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	if err := readMagic(r, deltaMagic); err != nil {
+		return err
+	}
+
+	blockSize, err := binary.ReadUvarint(r)
+	if err != nil {
+		return fmt.Errorf("invalid delta: %w", err)
+	}
+	if blockSize == 0 || blockSize > maxBlockSize {
+		return fmt.Errorf("invalid delta: block size %d", blockSize)
+	}
+	d.BlockSize = int(blockSize)
+
+	if _, err := io.ReadFull(r, d.Digest[:]); err != nil {
+		return fmt.Errorf("invalid delta: %w", err)
+	}
+
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return fmt.Errorf("invalid delta: %w", err)
+	}
+
+	d.Ops = nil
+	for i := uint64(0); i < count; i++ {
+		kind, err := r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("invalid delta: %w", err)
+		}
+
+		op := DeltaOp{Kind: DeltaOpKind(kind)}
+		switch op.Kind {
+		case DeltaCopy:
+			block, err := binary.ReadUvarint(r)
+			if err != nil {
+				return fmt.Errorf("invalid delta: %w", err)
+			}
+			count, err := binary.ReadUvarint(r)
+			if err != nil {
+				return fmt.Errorf("invalid delta: %w", err)
+			}
+			if block > math.MaxInt32 || count == 0 || count > math.MaxInt32 {
+				return errors.New("invalid delta: copy out of range")
+			}
+			op.Block, op.Count = int(block), int(count)
+
+		case DeltaData:
+			length, err := binary.ReadUvarint(r)
+			if err != nil {
+				return fmt.Errorf("invalid delta: %w", err)
+			}
+			if length > uint64(r.Len()) {
+				return errors.New("invalid delta: truncated data")
+			}
+			op.Data = make([]byte, length)
+			r.Read(op.Data)
+
+		default:
+			return fmt.Errorf("invalid delta: unknown operation %d", kind)
+		}
+
+		d.Ops = append(d.Ops, op)
+	}
+	return nil
+}
+
+// readMagic consumes the expected header from r.
+func readMagic(r io.Reader, magic []byte) error {
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, header); err != nil || !bytes.Equal(header, magic) {
+		return errors.New("invalid header")
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package filic_test
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"path"
+	"testing"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func randomBytes(seed int64, n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+func TestDeltaRoundTrip(t *testing.T) {
+	cleanup()
+
+	oldData := randomBytes(1, 100_000)
+
+	// the new version inserts, changes and removes data around the file
+	newData := append([]byte("inserted header"), oldData[:30_000]...)
+	newData = append(newData, []byte("changed middle")...)
+	newData = append(newData, oldData[30_100:90_000]...)
+
+	oldFile := filic.NewFile(path.Join(getTempDirPath(), "old.bin"))
+	newFile := filic.NewFile(path.Join(getTempDirPath(), "new.bin"))
+	oldFile.Create()
+	oldFile.Write(oldData)
+	newFile.Write(newData)
+
+	sig, err := oldFile.Signature(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	delta, err := newFile.Delta(sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	literal := 0
+	for _, op := range delta.Ops {
+		literal += len(op.Data)
+	}
+	if literal > 4096 {
+		t.Errorf("Expected most of the file to be reused, got %d literal bytes", literal)
+	}
+
+	err = oldFile.Patch(delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patched, err := oldFile.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(patched, newData) {
+		t.Error("Patched file doesn't match the new file")
+	}
+
+	cleanup()
+}
+
+func TestDeltaSerialization(t *testing.T) {
+	cleanup()
+
+	oldFile := filic.NewFile(path.Join(getTempDirPath(), "old.txt"))
+	newFile := filic.NewFile(path.Join(getTempDirPath(), "new.txt"))
+	oldFile.Create()
+	oldFile.Write([]byte("the quick brown fox jumps over the lazy dog"))
+	newFile.Write([]byte("the quick red fox jumps over the lazy dog!"))
+
+	sig, err := oldFile.Signature(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := sig.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decodedSig filic.Signature
+	if err := decodedSig.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	delta, err := newFile.Delta(&decodedSig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = delta.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decodedDelta filic.Delta
+	if err := decodedDelta.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := oldFile.Patch(&decodedDelta); err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := oldFile.ReadString()
+	if content != "the quick red fox jumps over the lazy dog!" {
+		t.Errorf("Unexpected patched content %q", content)
+	}
+
+	if err := decodedDelta.UnmarshalBinary([]byte("garbage")); err == nil {
+		t.Error("Expected error decoding garbage")
+	}
+
+	cleanup()
+}
+
+func TestPatchChecksumMismatch(t *testing.T) {
+	cleanup()
+
+	file := filic.NewFile(path.Join(getTempDirPath(), "file.txt"))
+	file.Create()
+	file.Write([]byte("original content"))
+
+	other := filic.NewFile(path.Join(getTempDirPath(), "other.txt"))
+	other.Write([]byte("something else"))
+
+	sig, _ := file.Signature(4)
+	delta, err := other.Delta(sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// corrupt the literal data
+	for i := range delta.Ops {
+		if delta.Ops[i].Kind == filic.DeltaData {
+			delta.Ops[i].Data[0] ^= 0xff
+		}
+	}
+
+	err = file.Patch(delta)
+	if !errors.Is(err, filic.ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, got %v", err)
+	}
+
+	content, _ := file.ReadString()
+	if content != "original content" {
+		t.Errorf("File should be unchanged, got %q", content)
+	}
+
+	cleanup()
+}
+
+func TestDeltaInvalidBlockSize(t *testing.T) {
+	cleanup()
+
+	file := filic.NewFile(path.Join(getTempDirPath(), "file.txt"))
+	file.Create()
+	file.Write([]byte("original content"))
+
+	if _, err := file.Delta(&filic.Signature{BlockSize: 0}); err == nil {
+		t.Error("Expected an error for a signature with a zero block size")
+	}
+
+	if _, err := file.Delta(&filic.Signature{BlockSize: 4, Size: 100}); err == nil {
+		t.Error("Expected an error for a signature missing blocks")
+	}
+
+	if err := file.Patch(&filic.Delta{BlockSize: 0}); err == nil {
+		t.Error("Expected an error for a delta with a zero block size")
+	}
+
+	content, _ := file.ReadString()
+	if content != "original content" {
+		t.Errorf("File should be unchanged, got %q", content)
+	}
+
+	// a block size uvarint that overflows int
+	data := append([]byte("FSIG\x01"), 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 0, 0)
+	var sig filic.Signature
+	if err := sig.UnmarshalBinary(data); err == nil {
+		t.Error("Expected an error decoding a signature with an overflowing block size")
+	}
+
+	data = append([]byte("FDLT\x01"), 0)
+	var delta filic.Delta
+	if err := delta.UnmarshalBinary(data); err == nil {
+		t.Error("Expected an error decoding a delta with a zero block size")
+	}
+
+	cleanup()
+}
@@ -0,0 +1,298 @@
+package filic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DiffKind describes how an entry differs between two directories.
+type DiffKind int
+
+const (
+	// DiffAdded marks an entry that only exists in the new directory.
+	DiffAdded DiffKind = iota
+	// DiffRemoved marks an entry that only exists in the old directory.
+	DiffRemoved
+	// DiffModified marks a file or symbolic link whose content changed.
+	DiffModified
+	// DiffTypeChanged marks an entry that changed between being a file,
+	// a directory or a symbolic link.
+	DiffTypeChanged
+)
+
+var diffKindNames = []string{"added", "removed", "modified", "type-changed"}
+
+// String returns the name of the kind, such as "added".
+func (k DiffKind) String() string {
+	if int(k) < len(diffKindNames) {
+		return diffKindNames[k]
+	}
+	return fmt.Sprintf("DiffKind(%d)", int(k))
+}
+
+// MarshalText encodes the kind as its name, so it reads well in JSON.
+func (k DiffKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText decodes a kind from its name.
+func (k *DiffKind) UnmarshalText(text []byte) error {
+	for i, name := range diffKindNames {
+		if name == string(text) {
+			*k = DiffKind(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown diff kind %q", text)
+}
+
+// CompareMode selects how files with the same path are compared.
+type CompareMode int
+
+const (
+	// CompareSizeModTime considers files modified when their size or
+	// modification time differ. It is fast but may report files that were
+	// touched without changing.
+	CompareSizeModTime CompareMode = iota
+	// CompareContent considers files modified when their size or SHA-256
+	// digest differ.
+	CompareContent
+)
+
+// DiffEntry is one difference between two directories.
+type DiffEntry struct {
+	// Path is the path of the entry relative to both directories.
+	Path string   `json:"path"`
+	Kind DiffKind `json:"kind"`
+	// OldType and NewType are "file", "dir", "symlink" or "other", and
+	// empty when the entry doesn't exist on that side.
+	OldType string `json:"oldType,omitempty"`
+	NewType string `json:"newType,omitempty"`
+	OldSize int64  `json:"oldSize,omitempty"`
+	NewSize int64  `json:"newSize,omitempty"`
+}
+
+// DiffReport lists the differences between an old and a new directory,
+// ordered by path.
+type DiffReport struct {
+	Old     string      `json:"old"`
+	New     string      `json:"new"`
+	Entries []DiffEntry `json:"entries"`
+}
+
+// DiffOptions configures DiffWithOptions.
+type DiffOptions struct {
+	// Compare selects how files present on both sides are compared.
+	Compare CompareMode
+	// Ignore, if set, excludes matching entries on both sides.
+	Ignore *IgnoreMatcher
+}
+
+// Diff compares the directory, as the old side, with other, as the new side,
+// and reports the entries that were added, removed, modified or changed
+// type. Files are compared by size and modification time. The contents of
+// added and removed directories are reported too.
+func (d *Directory) Diff(other *Directory) (*DiffReport, error) {
+	return d.DiffWithOptions(context.Background(), other, DiffOptions{})
+}
+
+// DiffWithOptions is like Diff but configured by opts. It stops and returns
+// the context's error once ctx is cancelled.
+func (d *Directory) DiffWithOptions(ctx context.Context, other *Directory, opts DiffOptions) (*DiffReport, error) {
+	oldTree, err := scanTree(ctx, d, opts.Ignore)
+	if err != nil {
+		return nil, err
+	}
+	newTree, err := scanTree(ctx, other, opts.Ignore)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := compareTrees(ctx, d.Path, other.Path, oldTree, newTree, opts.Compare)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DiffReport{Old: d.Path, New: other.Path, Entries: entries}, nil
+}
+
+// compareTrees compares two trees scanned by scanTree, rooted at oldRoot
+// and newRoot, and returns their differences ordered by path.
+func compareTrees(ctx context.Context, oldRoot, newRoot string, oldTree, newTree map[string]fs.FileInfo, mode CompareMode) ([]DiffEntry, error) {
+	paths := make([]string, 0, len(oldTree)+len(newTree))
+	for rel := range oldTree {
+		paths = append(paths, rel)
+	}
+	for rel := range newTree {
+		if _, ok := oldTree[rel]; !ok {
+			paths = append(paths, rel)
+		}
+	}
+	sort.Strings(paths)
+
+	var entries []DiffEntry
+	for _, rel := range paths {
+		oldInfo, inOld := oldTree[rel]
+		newInfo, inNew := newTree[rel]
+
+		entry := DiffEntry{Path: rel}
+		if inOld {
+			entry.OldType, entry.OldSize = entityType(oldInfo), oldInfo.Size()
+		}
+		if inNew {
+			entry.NewType, entry.NewSize = entityType(newInfo), newInfo.Size()
+		}
+
+		switch {
+		case !inOld:
+			entry.Kind = DiffAdded
+		case !inNew:
+			entry.Kind = DiffRemoved
+		case entry.OldType != entry.NewType:
+			entry.Kind = DiffTypeChanged
+		default:
+			modified, err := entriesDiffer(ctx, path.Join(oldRoot, rel), path.Join(newRoot, rel), oldInfo, newInfo, mode)
+			if err != nil {
+				return nil, err
+			}
+			if !modified {
+				continue
+			}
+			entry.Kind = DiffModified
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// scanTree walks dir and returns the FileInfo, without following symbolic
+// links, of every entry keyed by its relative path.
+func scanTree(ctx context.Context, dir *Directory, ignore *IgnoreMatcher) (map[string]fs.FileInfo, error) {
+	tree := map[string]fs.FileInfo{}
+	err := dir.WalkWithOptions(ctx, WalkOptions{Ignore: ignore}, func(entity *Entity) error {
+		info, err := entity.lstat()
+		if err != nil {
+			return newPathError("diff", entity.Path, err)
+		}
+		tree[relativePath(dir.Path, entity.Path)] = info
+		return nil
+	})
+	return tree, err
+}
+
+// entityType names the type of the entry described by info.
+func entityType(info fs.FileInfo) string {
+	switch {
+	case info.Mode().IsRegular():
+		return "file"
+	case info.IsDir():
+		return "dir"
+	case info.Mode()&fs.ModeSymlink != 0:
+		return "symlink"
+	}
+	return "other"
+}
+
+// entriesDiffer reports whether two entries of the same type differ.
+// Directories never differ themselves; their contents are compared
+// separately.
+func entriesDiffer(ctx context.Context, oldPath, newPath string, oldInfo, newInfo fs.FileInfo, mode CompareMode) (bool, error) {
+	switch entityType(oldInfo) {
+	case "symlink":
+		oldLink, err := os.Readlink(oldPath)
+		if err != nil {
+			return false, newPathError("diff", oldPath, err)
+		}
+		newLink, err := os.Readlink(newPath)
+		if err != nil {
+			return false, newPathError("diff", newPath, err)
+		}
+		return oldLink != newLink, nil
+
+	case "file":
+		if oldInfo.Size() != newInfo.Size() {
+			return true, nil
+		}
+		if mode == CompareSizeModTime {
+			return !oldInfo.ModTime().Equal(newInfo.ModTime()), nil
+		}
+
+		oldDigest, err := NewFile(oldPath).HashContext(ctx)
+		if err != nil {
+			return false, err
+		}
+		newDigest, err := NewFile(newPath).HashContext(ctx)
+		if err != nil {
+			return false, err
+		}
+		return oldDigest != newDigest, nil
+	}
+
+	return false, nil
+}
+
+// JSON returns the report encoded as indented JSON.
+func (r *DiffReport) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// Unified renders the changes of modified, added and removed text files in
+// the unified diff format, with contextLines lines of context. Binary files
+// are only mentioned, and other entries are left out.
+func (r *DiffReport) Unified(contextLines int) (string, error) {
+	var out strings.Builder
+
+	for _, entry := range r.Entries {
+		if entry.OldType != "file" && entry.NewType != "file" {
+			continue
+		}
+
+		oldName, newName := "a/"+entry.Path, "b/"+entry.Path
+		oldText, oldBinary, err := readDiffSide(r.Old, entry.Path, entry.OldType)
+		if err != nil {
+			return "", err
+		}
+		newText, newBinary, err := readDiffSide(r.New, entry.Path, entry.NewType)
+		if err != nil {
+			return "", err
+		}
+
+		if entry.OldType != "file" {
+			oldName = "/dev/null"
+		}
+		if entry.NewType != "file" {
+			newName = "/dev/null"
+		}
+
+		if oldBinary || newBinary {
+			fmt.Fprintf(&out, "Binary files %s and %s differ\n", oldName, newName)
+			continue
+		}
+
+		out.WriteString(UnifiedDiff(oldName, newName, oldText, newText, contextLines))
+	}
+
+	return out.String(), nil
+}
+
+// readDiffSide reads the file rel inside root for rendering, returning an
+// empty text when the entry is not a file on that side.
+func readDiffSide(root, rel, entryType string) (string, bool, error) {
+	if entryType != "file" {
+		return "", false, nil
+	}
+
+	data, err := NewFile(path.Join(root, rel)).Read()
+	if err != nil {
+		return "", false, err
+	}
+	return string(data), isBinary(data), nil
+}
@@ -0,0 +1,168 @@
+package filic_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	diff := filic.UnifiedDiff("a.txt", "b.txt", "one\ntwo\nthree\n", "one\n2\nthree\nfour\n", 1)
+
+	expected := "--- a.txt\n+++ b.txt\n@@ -1,3 +1,4 @@\n one\n-two\n+2\n three\n+four\n"
+	if diff != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, diff)
+	}
+
+	// changes separated by twice the context share a hunk, like GNU diff
+	diff = filic.UnifiedDiff("a", "b", "1\n2\n3\n4\n5\n6\n", "1\nX\n3\n4\nY\n6\n", 1)
+	expected = "--- a\n+++ b\n@@ -1,6 +1,6 @@\n 1\n-2\n+X\n 3\n 4\n-5\n+Y\n 6\n"
+	if diff != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, diff)
+	}
+
+	diff = filic.UnifiedDiff("a", "b", "1\n2\n3\n4\n5\n6\n7\n", "1\nX\n3\n4\n5\nY\n7\n", 1)
+	if strings.Count(diff, "@@ -") != 2 {
+		t.Errorf("Expected changes further apart to be in separate hunks, got:\n%s", diff)
+	}
+
+	if filic.UnifiedDiff("a", "b", "same\n", "same\n", 3) != "" {
+		t.Error("Expected no diff for equal texts")
+	}
+}
+
+func TestUnifiedDiffLargeTexts(t *testing.T) {
+	// entirely different texts are the worst case of the diff algorithm
+	var oldText, newText strings.Builder
+	for i := range 5000 {
+		fmt.Fprintf(&oldText, "old %d\n", i)
+		fmt.Fprintf(&newText, "new %d\n", i)
+	}
+
+	diff := filic.UnifiedDiff("a", "b", oldText.String(), newText.String(), 3)
+	if strings.Count(diff, "\n-old ") != 5000 || strings.Count(diff, "\n+new ") != 5000 {
+		t.Errorf("Expected every line to be replaced, got %d bytes of diff", len(diff))
+	}
+}
+
+func setupDiffTrees(t *testing.T) (*filic.Directory, *filic.Directory) {
+	t.Helper()
+
+	createTree(t, map[string]string{
+		"old/same.txt":     "same",
+		"old/changed.txt":  "line1\nline2\n",
+		"old/removed.txt":  "gone",
+		"old/retyped":      "file",
+		"old/touched.txt":  "touched",
+		"new/same.txt":     "same",
+		"new/changed.txt":  "line1\nline two\n",
+		"new/added/x.txt":  "x",
+		"new/retyped/file": "now a dir",
+		"new/touched.txt":  "touched",
+	})
+
+	oldDir := filic.NewDirectory(path.Join(getTempDirPath(), "old"))
+	newDir := filic.NewDirectory(path.Join(getTempDirPath(), "new"))
+
+	// same.txt keeps the same modification time on both sides
+	stamp := time.Now().Add(-time.Hour)
+	os.Chtimes(oldDir.Join("same.txt"), stamp, stamp)
+	os.Chtimes(newDir.Join("same.txt"), stamp, stamp)
+	os.Chtimes(oldDir.Join("touched.txt"), stamp, stamp)
+
+	return oldDir, newDir
+}
+
+func TestDirectoryDiff(t *testing.T) {
+	cleanup()
+
+	oldDir, newDir := setupDiffTrees(t)
+
+	report, err := oldDir.Diff(newDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]filic.DiffKind{
+		"added":        filic.DiffAdded,
+		"added/x.txt":  filic.DiffAdded,
+		"changed.txt":  filic.DiffModified,
+		"removed.txt":  filic.DiffRemoved,
+		"retyped":      filic.DiffTypeChanged,
+		"retyped/file": filic.DiffAdded,
+		"touched.txt":  filic.DiffModified,
+	}
+
+	if len(report.Entries) != len(expected) {
+		t.Errorf("Expected %d entries, got %+v", len(expected), report.Entries)
+	}
+	for _, entry := range report.Entries {
+		if kind, ok := expected[entry.Path]; !ok || kind != entry.Kind {
+			t.Errorf("Unexpected entry %+v", entry)
+		}
+	}
+
+	// comparing content, touched.txt is unchanged
+	report, err = oldDir.DiffWithOptions(context.Background(), newDir, filic.DiffOptions{Compare: filic.CompareContent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range report.Entries {
+		if entry.Path == "touched.txt" {
+			t.Error("touched.txt should not be reported when comparing content")
+		}
+	}
+
+	cleanup()
+}
+
+func TestDiffReportRendering(t *testing.T) {
+	cleanup()
+
+	oldDir, newDir := setupDiffTrees(t)
+
+	report, err := oldDir.DiffWithOptions(context.Background(), newDir, filic.DiffOptions{Compare: filic.CompareContent})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unified, err := report.Unified(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		"--- a/changed.txt\n+++ b/changed.txt\n@@ -1,2 +1,2 @@\n line1\n-line2\n+line two\n",
+		"--- a/removed.txt\n+++ /dev/null\n",
+		"--- /dev/null\n+++ b/added/x.txt\n",
+	} {
+		if !strings.Contains(unified, expected) {
+			t.Errorf("Expected unified diff to contain:\n%s\ngot:\n%s", expected, unified)
+		}
+	}
+
+	data, err := report.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded filic.DiffReport
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Entries) != len(report.Entries) {
+		t.Errorf("Expected %d entries after decoding, got %d", len(report.Entries), len(decoded.Entries))
+	}
+	if !strings.Contains(string(data), `"kind": "type-changed"`) {
+		t.Errorf("Expected kinds to be encoded by name, got %s", data)
+	}
+
+	cleanup()
+}
@@ -1,8 +1,10 @@
 package filic
 
 import (
-	"fmt"
+	"context"
 	"os"
+	"path"
+	"strings"
 )
 
 // Directory represents a directory in the file system. It embeds Entity
@@ -19,17 +21,32 @@ func (d *Directory) Create() error {
 	if d.Exists() {
 		return nil
 	}
-	return os.MkdirAll(d.Path, 0755)
+	return newPathError("mkdir", d.Path, os.MkdirAll(d.Path, 0755))
+}
+
+// child returns the path of the entry with the given name inside the
+// directory. Names that would resolve outside of the directory, such as
+// "../sibling", are rejected with ErrOutsideRoot.
+func (d *Directory) child(op, name string) (string, error) {
+	clean := path.Clean(name)
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", &PathError{Op: op, Path: d.Join(name), Err: ErrOutsideRoot}
+	}
+	return d.Join(name), nil
 }
 
 // OpenDir opens or prepares to open a subdirectory with the given name.
 // It returns a Directory instance for the child directory. If a file system
 // entity already exists at the target path but is not a directory, it returns
-// an error. If the path doesn't exist, it returns a Directory that can be
-// created later using the Create method.
+// an error matching ErrNotDirectory. If the path doesn't exist, it returns a
+// Directory that can be created later using the Create method. Names that
+// escape the directory are rejected with ErrOutsideRoot.
 func (d *Directory) OpenDir(name string) (*Directory, error) {
 
-	path := d.Join(name)
+	path, err := d.child("opendir", name)
+	if err != nil {
+		return nil, err
+	}
 
 	entity := NewEntity(path)
 
@@ -43,7 +60,7 @@ func (d *Directory) OpenDir(name string) (*Directory, error) {
 		}
 
 		if !isDir {
-			return nil, fmt.Errorf("path %v exists but its not a directory", path)
+			return nil, &PathError{Op: "opendir", Path: path, Err: ErrNotDirectory}
 		}
 
 	}
@@ -53,11 +70,16 @@ func (d *Directory) OpenDir(name string) (*Directory, error) {
 
 // OpenFile opens or prepares to open a file with the given name within this directory.
 // It returns a File instance for the target file. If a file system entity already
-// exists at the target path but is a directory, it returns an error. If the path
-// doesn't exist, it returns a File that can be created later using file operations.
+// exists at the target path but is a directory, it returns an error matching
+// ErrIsDirectory. If the path doesn't exist, it returns a File that can be created
+// later using file operations. Names that escape the directory are rejected with
+// ErrOutsideRoot.
 func (d *Directory) OpenFile(name string) (*File, error) {
 
-	path := d.Join(name)
+	path, err := d.child("openfile", name)
+	if err != nil {
+		return nil, err
+	}
 
 	entity := NewEntity(path)
 
@@ -71,7 +93,7 @@ func (d *Directory) OpenFile(name string) (*File, error) {
 		}
 
 		if isDir {
-			return nil, fmt.Errorf("path %v exists but its a directory", path)
+			return nil, &PathError{Op: "openfile", Path: path, Err: ErrIsDirectory}
 		}
 
 	}
@@ -82,9 +104,15 @@ func (d *Directory) OpenFile(name string) (*File, error) {
 // List returns a list of all the files and directories in the directory.
 // It returns an error if the directory doesn't exist or cannot be read.
 func (d *Directory) List() ([]string, error) {
-	files, err := os.ReadDir(d.Path)
+	return d.ListContext(context.Background())
+}
+
+// ListContext is like List but stops and returns the context's error once
+// ctx is cancelled, which matters for directories with very many entries.
+func (d *Directory) ListContext(ctx context.Context) ([]string, error) {
+	files, err := readDirContext(ctx, d.Path)
 	if err != nil {
-		return nil, err
+		return nil, newPathError("list", d.Path, err)
 	}
 
 	var names []string
@@ -97,18 +125,19 @@ func (d *Directory) List() ([]string, error) {
 
 // ListAsEntities returns a list of all items in the directory as Entity instances.
 // This provides a unified way to work with both files and directories, allowing
-// you to check their type using the IsDirectory method. Returns an error if the
-// directory doesn't exist or cannot be read.
+// you to check their type using the IsDirectory method. The entities keep the
+// type information read from the directory, so IsDirectory doesn't need another
+// system call for them. Returns an error if the directory doesn't exist or
+// cannot be read.
 func (d *Directory) ListAsEntities() ([]Entity, error) {
-	names, err := d.List()
+	entries, err := readDirContext(context.Background(), d.Path)
 	if err != nil {
-		return nil, err
+		return nil, newPathError("list", d.Path, err)
 	}
 
 	var entities []Entity
-	for _, name := range names {
-		entity := NewEntity(d.Join(name))
-		entities = append(entities, *entity)
+	for _, entry := range entries {
+		entities = append(entities, *newListedEntity(d.Path, entry))
 	}
 
 	return entities, nil
@@ -133,7 +162,7 @@ func (d *Directory) ListDirectories() ([]*Directory, error) {
 		}
 
 		if isDir {
-			directories = append(directories, NewDirectory(entity.Path))
+			directories = append(directories, &Directory{Entity: entity})
 		}
 	}
 	return directories, nil
@@ -158,7 +187,7 @@ func (d *Directory) ListFiles() ([]*File, error) {
 		}
 
 		if !isDir {
-			files = append(files, NewFile(entity.Path))
+			files = append(files, &File{Entity: entity})
 		}
 	}
 	return files, nil
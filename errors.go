@@ -0,0 +1,107 @@
+package filic
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// Sentinel errors returned (wrapped in a *PathError) by filic operations.
+// ErrNotExist and ErrExist are the standard fs sentinels, so errors.Is
+// works the same way it does for errors returned by the os package.
+var (
+	ErrNotExist     = fs.ErrNotExist
+	ErrExist        = fs.ErrExist
+	ErrNotDirectory = errors.New("not a directory")
+	ErrIsDirectory  = errors.New("is a directory")
+	ErrOutsideRoot  = errors.New("path escapes the root directory")
+
+	// ErrChecksumMismatch is returned when content doesn't match the
+	// checksum it is expected to have.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+
+	// ErrTxDone is returned when using a transaction that was already
+	// committed or rolled back.
+	ErrTxDone = errors.New("transaction already committed or rolled back")
+
+	// ErrRecordTooLarge is returned when appending a record larger than a
+	// RecordLog can store.
+	ErrRecordTooLarge = errors.New("record too large")
+
+	// ErrCorruptRecord is returned when a RecordLog is damaged before its
+	// last record, where an interrupted append cannot have left it.
+	ErrCorruptRecord = errors.New("corrupt record")
+
+	// ErrInvalidDigest is returned when a blob digest is not a lowercase hex
+	// encoded SHA-256 digest.
+	ErrInvalidDigest = errors.New("invalid digest")
+
+	// ErrSameFile is returned when copying a file onto itself.
+	ErrSameFile = errors.New("source and destination are the same file")
+
+	// ErrInvalidKey is returned for a key-value store key that is empty or
+	// too long to be stored.
+	ErrInvalidKey = errors.New("invalid key")
+)
+
+// PathError records an error together with the filic operation and the path
+// that caused it. It unwraps to the underlying cause, so both the filic and
+// the standard fs sentinels can be matched with errors.Is.
+type PathError struct {
+	Op   string
+	Path string
+	Err  error
+}
+
+// Error returns the error in the form "op path: cause".
+func (e *PathError) Error() string {
+	return e.Op + " " + e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying cause of the error.
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// newPathError wraps err in a *PathError for the given operation and path.
+// Errors coming from the os package already carry a path, so their inner
+// cause is used instead to avoid repeating it. A nil err returns nil.
+func newPathError(op, path string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var fsErr *fs.PathError
+	var linkErr *os.LinkError
+	var filicErr *PathError
+
+	switch {
+	case errors.As(err, &filicErr):
+		return err
+	case errors.As(err, &fsErr):
+		err = fsErr.Err
+	case errors.As(err, &linkErr):
+		err = linkErr.Err
+	}
+
+	if sentinel := sysErrorSentinel(err); sentinel != nil {
+		err = &sysError{sentinel: sentinel, err: err}
+	}
+
+	return &PathError{Op: op, Path: path, Err: err}
+}
+
+// sysError is a system error matching the filic sentinel it corresponds
+// to, as well as itself.
+type sysError struct {
+	sentinel error
+	err      error
+}
+
+func (e *sysError) Error() string {
+	return e.err.Error()
+}
+
+func (e *sysError) Unwrap() []error {
+	return []error{e.sentinel, e.err}
+}
@@ -0,0 +1,123 @@
+package filic_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func TestOpenDirErrNotDirectory(t *testing.T) {
+	cleanup()
+
+	dir := filic.NewDirectory(getTempDirPath())
+	dir.Create()
+
+	f, err := os.Create(dir.Join("subfile"))
+	if err != nil {
+		t.Error(err)
+	}
+	f.Close()
+
+	_, err = dir.OpenDir("subfile")
+	if !errors.Is(err, filic.ErrNotDirectory) {
+		t.Errorf("Expected ErrNotDirectory, got %v", err)
+	}
+
+	var pathErr *filic.PathError
+	if !errors.As(err, &pathErr) {
+		t.Fatalf("Expected *PathError, got %T", err)
+	}
+
+	if pathErr.Path != dir.Join("subfile") {
+		t.Errorf("Expected path %v, got %v", dir.Join("subfile"), pathErr.Path)
+	}
+
+	cleanup()
+}
+
+func TestOpenFileErrIsDirectory(t *testing.T) {
+	cleanup()
+
+	dir := filic.NewDirectory(getTempDirPath())
+	os.MkdirAll(dir.Join("subdir"), 0755)
+
+	_, err := dir.OpenFile("subdir")
+	if !errors.Is(err, filic.ErrIsDirectory) {
+		t.Errorf("Expected ErrIsDirectory, got %v", err)
+	}
+
+	cleanup()
+}
+
+func TestOpenErrOutsideRoot(t *testing.T) {
+	dir := filic.NewDirectory(getTempDirPath())
+
+	_, err := dir.OpenFile("../escape.txt")
+	if !errors.Is(err, filic.ErrOutsideRoot) {
+		t.Errorf("Expected ErrOutsideRoot, got %v", err)
+	}
+
+	_, err = dir.OpenDir("a/../../escape")
+	if !errors.Is(err, filic.ErrOutsideRoot) {
+		t.Errorf("Expected ErrOutsideRoot, got %v", err)
+	}
+
+	_, err = dir.OpenDir("a/../b")
+	if err != nil {
+		t.Errorf("Expected no error for a path inside the directory, got %v", err)
+	}
+}
+
+func TestErrNotExist(t *testing.T) {
+	cleanup()
+
+	file := filic.NewFile(getTempDirPath() + "/missing.txt")
+
+	_, err := file.Read()
+	if !errors.Is(err, filic.ErrNotExist) {
+		t.Errorf("Expected ErrNotExist, got %v", err)
+	}
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected fs.ErrNotExist, got %v", err)
+	}
+
+	err = file.Append([]byte("data"))
+	if !errors.Is(err, filic.ErrNotExist) {
+		t.Errorf("Expected ErrNotExist, got %v", err)
+	}
+
+	var pathErr *filic.PathError
+	if !errors.As(err, &pathErr) || pathErr.Op != "append" {
+		t.Errorf("Expected *PathError with op append, got %v", err)
+	}
+
+	_, err = filic.NewDirectory(getTempDirPath()).List()
+	if !errors.Is(err, filic.ErrNotExist) {
+		t.Errorf("Expected ErrNotExist, got %v", err)
+	}
+}
+
+func TestSystemErrorsMatchSentinels(t *testing.T) {
+	cleanup()
+
+	dir := filic.NewDirectory(getTempDirPath())
+	os.MkdirAll(dir.Join("subdir"), 0755)
+	os.WriteFile(dir.Join("subfile"), nil, 0644)
+
+	_, err := filic.NewDirectory(dir.Join("subfile")).List()
+	if !errors.Is(err, filic.ErrNotDirectory) || !errors.Is(err, syscall.ENOTDIR) {
+		t.Errorf("Expected ErrNotDirectory, got %v", err)
+	}
+
+	err = filic.NewFile(dir.Join("subdir")).Write([]byte("data"))
+	if !errors.Is(err, filic.ErrIsDirectory) {
+		t.Errorf("Expected ErrIsDirectory, got %v", err)
+	}
+
+	cleanup()
+}
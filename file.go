@@ -35,13 +35,17 @@ func (f *File) Create() error {
 // The file is created if it doesn't exist, and parent directories are not
 // automatically created. The file is written with 0644 permissions (rw-r--r--).
 func (f *File) Write(data []byte) error {
-	return os.WriteFile(f.Path, data, 0644)
+	return newPathError("write", f.Path, os.WriteFile(f.Path, data, 0644))
 }
 
 // Read reads the entire contents of the file and returns it as a byte slice.
 // It returns an error if the file doesn't exist or cannot be read.
 func (f *File) Read() ([]byte, error) {
-	return os.ReadFile(f.Path)
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, newPathError("read", f.Path, err)
+	}
+	return data, nil
 }
 
 // ReadString reads the entire contents of the file and returns it as a string.
@@ -59,13 +63,13 @@ func (f *File) ReadString() (string, error) {
 func (f *File) Append(data []byte) error {
 	file, err := os.OpenFile(f.Path, os.O_APPEND|os.O_WRONLY, 0644)
 	if err != nil {
-		return err
+		return newPathError("append", f.Path, err)
 	}
 	defer file.Close()
 
 	_, err = file.Write(data)
 	if err != nil {
-		return err
+		return newPathError("append", f.Path, err)
 	}
 
 	return nil
@@ -1,8 +1,12 @@
 package filic_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"os"
 	"path"
+	"strings"
 	"testing"
 
 	"github.com/henilmalaviya/filic"
@@ -282,3 +286,57 @@ func TestFileOperationsChain(t *testing.T) {
 
 	cleanup()
 }
+
+func TestHashFile(t *testing.T) {
+	cleanup()
+
+	file := filic.NewFile(path.Join(getTempDirPath(), "hash.txt"))
+	file.Create()
+	file.Write([]byte("hello world"))
+
+	digest, err := file.Hash()
+	if err != nil {
+		t.Error(err)
+	}
+
+	expected := "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	if digest != expected {
+		t.Errorf("Expected %v, got %v", expected, digest)
+	}
+
+	cleanup()
+}
+
+func TestReadToAndWriteFrom(t *testing.T) {
+	cleanup()
+
+	file := filic.NewFile(path.Join(getTempDirPath(), "stream.txt"))
+	file.Create()
+
+	n, err := file.WriteFrom(strings.NewReader("streamed content"))
+	if err != nil {
+		t.Error(err)
+	}
+	if n != int64(len("streamed content")) {
+		t.Errorf("Expected %d bytes written, got %d", len("streamed content"), n)
+	}
+
+	var buf bytes.Buffer
+	_, err = file.ReadTo(&buf)
+	if err != nil {
+		t.Error(err)
+	}
+	if buf.String() != "streamed content" {
+		t.Errorf("Expected %q, got %q", "streamed content", buf.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = file.ReadToContext(ctx, &buf)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	cleanup()
+}
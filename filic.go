@@ -1,6 +1,7 @@
 package filic
 
 import (
+	"io/fs"
 	"os"
 	"path"
 )
@@ -19,22 +20,77 @@ type FileSystemEntity interface {
 // Entity represents a file system entity (file or directory) with a specific path.
 // It embeds the FileSystemEntity interface and provides concrete implementations
 // for common file system operations.
+//
+// Entities returned by directory listings and walks remember the type bits
+// read from the directory, and lazily cache their FileInfo, so inspecting
+// them doesn't cost an extra system call per entry. That information reflects
+// the entry at the time it was listed.
 type Entity struct {
 	FileSystemEntity
 	Path string
+
+	entry fs.DirEntry
+	info  fs.FileInfo
 }
 
 // IsDirectory checks whether the entity at the current path is a directory.
 // It returns true if the path points to a directory, false if it's a file,
-// and an error if the path cannot be accessed or doesn't exist.
+// and an error if the path cannot be accessed or doesn't exist. Symbolic
+// links are followed.
 func (e *Entity) IsDirectory() (bool, error) {
-	info, err := os.Stat(e.Path)
+	if e.entry != nil && e.entry.Type()&fs.ModeSymlink == 0 {
+		return e.entry.IsDir(), nil
+	}
+
+	info, err := e.Info()
 	if err != nil {
 		return false, err
 	}
 	return info.IsDir(), nil
 }
 
+// Info returns the FileInfo describing the entity, following symbolic links.
+// For entities that came from a listing the result is cached after the first
+// call; otherwise the file system is queried every time.
+func (e *Entity) Info() (fs.FileInfo, error) {
+	if e.info != nil {
+		return e.info, nil
+	}
+
+	var info fs.FileInfo
+	var err error
+	if e.entry != nil && e.entry.Type()&fs.ModeSymlink == 0 {
+		info, err = e.entry.Info()
+	} else {
+		info, err = os.Stat(e.Path)
+	}
+	if err != nil {
+		return nil, newPathError("stat", e.Path, err)
+	}
+
+	if e.entry != nil {
+		e.info = info
+	}
+	return info, nil
+}
+
+// lstat returns the FileInfo of the entity without following symbolic links,
+// using the listed entry when there is one.
+func (e *Entity) lstat() (fs.FileInfo, error) {
+	if e.entry != nil {
+		if e.info != nil && e.entry.Type()&fs.ModeSymlink == 0 {
+			return e.info, nil
+		}
+		return e.entry.Info()
+	}
+	return os.Lstat(e.Path)
+}
+
+// Name returns the last element of the entity's path.
+func (e *Entity) Name() string {
+	return path.Base(e.Path)
+}
+
 // Join creates a new path by joining the current entity's path with the given name.
 // This is useful for creating paths to child files or directories.
 // The resulting path uses the appropriate path separator for the operating system.
@@ -56,6 +112,18 @@ func (e *Entity) Exists() bool {
 	return err == nil
 }
 
+// Delete removes the entity from the file system. Directories are removed
+// together with everything they contain. Deleting an entity that doesn't
+// exist is not an error.
+func (e *Entity) Delete() error {
+	return newPathError("delete", e.Path, os.RemoveAll(e.Path))
+}
+
+// Chmod changes the permission bits of the entity to mode.
+func (e *Entity) Chmod(mode fs.FileMode) error {
+	return newPathError("chmod", e.Path, os.Chmod(e.Path, mode))
+}
+
 // NewEntity creates a new Entity instance with the specified path.
 // The path can point to either a file or directory - the actual type
 // can be determined later using the IsDirectory method.
@@ -64,3 +132,12 @@ func NewEntity(path string) *Entity {
 		Path: path,
 	}
 }
+
+// newListedEntity creates an Entity for an entry read from the directory dir,
+// keeping the entry so its type and info don't have to be read again.
+func newListedEntity(dir string, entry fs.DirEntry) *Entity {
+	return &Entity{
+		Path:  path.Join(dir, entry.Name()),
+		entry: entry,
+	}
+}
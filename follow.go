@@ -0,0 +1,300 @@
+package filic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"os"
+	"path"
+	"time"
+)
+
+// defaultFollowPollInterval is used when FollowOptions.PollInterval is zero.
+const defaultFollowPollInterval = 250 * time.Millisecond
+
+// followChunkSize is the size of the reads done while following a file.
+const followChunkSize = 32 * 1024
+
+// FollowOptions configures FollowWithOptions.
+type FollowOptions struct {
+	// Lines starts following this many lines before the end of the file.
+	// Zero starts at the end, yielding only data appended afterwards.
+	Lines int
+	// Raw yields data in chunks as it is read instead of splitting it into
+	// lines.
+	Raw bool
+	// PollInterval is how often the file is checked for new data when no
+	// change notification arrives. It defaults to 250ms.
+	PollInterval time.Duration
+}
+
+// Follow yields lines appended to the file, without their trailing newline,
+// like `tail -F`. It keeps following the file across truncation, and when
+// the file is rotated (replaced by a new file at the same path) it finishes
+// reading the old file and continues with the new one from its start.
+//
+// Follow runs until ctx is cancelled, and then yields ctx's error. On Linux
+// changes are noticed through inotify; elsewhere the file is polled.
+func (f *File) Follow(ctx context.Context) iter.Seq2[[]byte, error] {
+	return f.FollowWithOptions(ctx, FollowOptions{})
+}
+
+// FollowWithOptions is like Follow but configured by opts.
+func (f *File) FollowWithOptions(ctx context.Context, opts FollowOptions) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		fl := &follower{path: f.Path, opts: opts, yield: yield}
+		if fl.opts.PollInterval <= 0 {
+			fl.opts.PollInterval = defaultFollowPollInterval
+		}
+
+		if err := fl.follow(ctx); err != nil && !errors.Is(err, errStopFollow) {
+			yield(nil, err)
+		}
+	}
+}
+
+// errStopFollow signals that the consumer stopped the iteration.
+var errStopFollow = errors.New("stop following")
+
+// followWaiter blocks until the followed file may have changed.
+type followWaiter interface {
+	// wait returns when a change is noticed, timeout passes or ctx is
+	// cancelled, whichever happens first.
+	wait(ctx context.Context, timeout time.Duration)
+	Close() error
+}
+
+// pollWaiter is the followWaiter used when change notifications are not
+// available: it just sleeps.
+type pollWaiter struct{}
+
+func (pollWaiter) wait(ctx context.Context, timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+func (pollWaiter) Close() error { return nil }
+
+type follower struct {
+	path    string
+	opts    FollowOptions
+	yield   func([]byte, error) bool
+	file    *os.File
+	info    os.FileInfo
+	offset  int64
+	pending []byte
+}
+
+func (fl *follower) follow(ctx context.Context) error {
+	if err := fl.open(); err != nil {
+		return err
+	}
+	defer func() { fl.file.Close() }()
+
+	start, err := tailOffset(fl.file, fl.info.Size(), fl.opts.Lines)
+	if err != nil {
+		return newPathError("follow", fl.path, err)
+	}
+	if err := fl.seek(start); err != nil {
+		return err
+	}
+
+	waiter, err := newFollowWaiter(path.Dir(fl.path))
+	if err != nil {
+		waiter = pollWaiter{}
+	}
+	defer waiter.Close()
+
+	buf := make([]byte, followChunkSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, err := fl.file.Read(buf)
+		if n > 0 {
+			fl.offset += int64(n)
+			if err := fl.emit(buf[:n]); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return newPathError("follow", fl.path, err)
+		}
+
+		changed, err := fl.checkFile()
+		if err != nil {
+			return err
+		}
+		if !changed {
+			waiter.wait(ctx, fl.opts.PollInterval)
+		}
+	}
+}
+
+// open opens the file at the followed path.
+func (fl *follower) open() error {
+	file, err := os.Open(fl.path)
+	if err != nil {
+		return newPathError("follow", fl.path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return newPathError("follow", fl.path, err)
+	}
+
+	fl.file, fl.info, fl.offset = file, info, 0
+	return nil
+}
+
+func (fl *follower) seek(offset int64) error {
+	if _, err := fl.file.Seek(offset, io.SeekStart); err != nil {
+		return newPathError("follow", fl.path, err)
+	}
+	fl.offset = offset
+	return nil
+}
+
+// checkFile is called at the end of the open file and handles truncation
+// and rotation. It reports whether reading should resume right away.
+func (fl *follower) checkFile() (bool, error) {
+	info, err := os.Stat(fl.path)
+	if err != nil {
+		// rotated away and not yet replaced
+		return false, nil
+	}
+
+	if !os.SameFile(fl.info, info) {
+		// what was written to the old file before it was replaced comes
+		// first
+		if err := fl.drain(); err != nil {
+			return false, err
+		}
+
+		old := fl.file
+		if err := fl.open(); err != nil {
+			// the new file disappeared again; keep waiting on the old one
+			return false, nil
+		}
+		old.Close()
+		return true, fl.flush()
+	}
+
+	if info.Size() < fl.offset {
+		if err := fl.flush(); err != nil {
+			return false, err
+		}
+		return true, fl.seek(0)
+	}
+	return false, nil
+}
+
+// drain reads the open file to its end.
+func (fl *follower) drain() error {
+	buf := make([]byte, followChunkSize)
+	for {
+		n, err := fl.file.Read(buf)
+		if n > 0 {
+			fl.offset += int64(n)
+			if err := fl.emit(buf[:n]); err != nil {
+				return err
+			}
+			continue
+		}
+		if err == nil || err == io.EOF {
+			return nil
+		}
+		return newPathError("follow", fl.path, err)
+	}
+}
+
+// emit yields data, split into lines unless following in raw mode. An
+// incomplete last line is kept until the rest of it arrives.
+func (fl *follower) emit(data []byte) error {
+	if fl.opts.Raw {
+		return fl.send(bytes.Clone(data))
+	}
+
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			fl.pending = append(fl.pending, data...)
+			return nil
+		}
+
+		line := append(fl.pending, data[:i]...)
+		fl.pending = nil
+		data = data[i+1:]
+
+		if err := fl.send(line); err != nil {
+			return err
+		}
+	}
+}
+
+// flush yields an incomplete last line, which will never be completed
+// because the file was truncated or rotated.
+func (fl *follower) flush() error {
+	if len(fl.pending) == 0 {
+		return nil
+	}
+	line := fl.pending
+	fl.pending = nil
+	return fl.send(line)
+}
+
+func (fl *follower) send(data []byte) error {
+	if !fl.yield(data, nil) {
+		return errStopFollow
+	}
+	return nil
+}
+
+// tailOffset returns the offset of the start of the last n lines of the
+// file, ignoring a newline terminating the file.
+func tailOffset(file *os.File, size int64, n int) (int64, error) {
+	if n <= 0 {
+		return size, nil
+	}
+
+	end := size
+	if end > 0 {
+		var last [1]byte
+		if _, err := file.ReadAt(last[:], end-1); err != nil {
+			return 0, err
+		}
+		if last[0] == '\n' {
+			end--
+		}
+	}
+
+	buf := make([]byte, followChunkSize)
+	for end > 0 {
+		start := max(end-int64(len(buf)), 0)
+		chunk := buf[:end-start]
+		if _, err := file.ReadAt(chunk, start); err != nil {
+			return 0, err
+		}
+
+		for i := len(chunk) - 1; i >= 0; i-- {
+			if chunk[i] == '\n' {
+				n--
+				if n == 0 {
+					return start + int64(i) + 1, nil
+				}
+			}
+		}
+		end = start
+	}
+	return 0, nil
+}
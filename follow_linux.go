@@ -0,0 +1,48 @@
+//go:build linux
+
+package filic
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"time"
+)
+
+// inotifyWaiter wakes up on inotify events in the directory of the followed
+// file, which covers both writes to the file and it being replaced.
+type inotifyWaiter struct {
+	file *os.File
+}
+
+func newFollowWaiter(dir string) (followWaiter, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	const mask = syscall.IN_MODIFY | syscall.IN_ATTRIB | syscall.IN_CREATE | syscall.IN_DELETE |
+		syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_CLOSE_WRITE
+	if _, err := syscall.InotifyAddWatch(fd, dir, mask); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	// a non-blocking descriptor is handled by the runtime poller, so reads
+	// honor deadlines
+	return &inotifyWaiter{file: os.NewFile(uintptr(fd), "inotify")}, nil
+}
+
+func (w *inotifyWaiter) wait(ctx context.Context, timeout time.Duration) {
+	w.file.SetReadDeadline(time.Now().Add(timeout))
+	stop := context.AfterFunc(ctx, func() { w.file.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	// the events themselves don't matter, the follower checks the file
+	var buf [4096]byte
+	w.file.Read(buf[:])
+}
+
+func (w *inotifyWaiter) Close() error {
+	return w.file.Close()
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package filic
+
+// newFollowWaiter returns a waiter polling the followed file, as change
+// notifications are not supported on this platform.
+func newFollowWaiter(dir string) (followWaiter, error) {
+	return pollWaiter{}, nil
+}
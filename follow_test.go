@@ -0,0 +1,151 @@
+package filic_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/henilmalaviya/filic"
+)
+
+// startFollow follows file in the background, sending what it yields to
+// the returned channel.
+func startFollow(ctx context.Context, file *filic.File, opts filic.FollowOptions) <-chan string {
+	lines := make(chan string, 100)
+	go func() {
+		defer close(lines)
+		for line, err := range file.FollowWithOptions(ctx, opts) {
+			if err != nil {
+				lines <- "error: " + err.Error()
+				return
+			}
+			lines <- string(line)
+		}
+	}()
+	return lines
+}
+
+func expectLines(t *testing.T, lines <-chan string, expected ...string) {
+	t.Helper()
+
+	for _, want := range expected {
+		select {
+		case got := <-lines:
+			if got != want {
+				t.Fatalf("Expected %q, got %q", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for %q", want)
+		}
+	}
+}
+
+func TestFollowLinesBack(t *testing.T) {
+	cleanup()
+
+	dir := filic.NewDirectory(getTempDirPath())
+	dir.Create()
+	file := filic.NewFile(dir.Join("app.log"))
+	file.Write([]byte("one\ntwo\nthree\n"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lines := startFollow(ctx, file, filic.FollowOptions{Lines: 2, PollInterval: 20 * time.Millisecond})
+	expectLines(t, lines, "two", "three")
+
+	file.Append([]byte("fo"))
+	file.Append([]byte("ur\nfive\n"))
+	expectLines(t, lines, "four", "five")
+
+	cancel()
+	expectLines(t, lines, "error: "+context.Canceled.Error())
+
+	cleanup()
+}
+
+func TestFollowTruncateAndRotate(t *testing.T) {
+	cleanup()
+
+	dir := filic.NewDirectory(getTempDirPath())
+	dir.Create()
+	file := filic.NewFile(dir.Join("app.log"))
+	file.Write([]byte("old content that is long\n"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lines := startFollow(ctx, file, filic.FollowOptions{PollInterval: 20 * time.Millisecond})
+
+	// give the follower time to reach the end before changing the file
+	time.Sleep(100 * time.Millisecond)
+
+	file.Write([]byte("truncated\n"))
+	expectLines(t, lines, "truncated")
+
+	if err := os.Rename(file.Path, dir.Join("app.log.1")); err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte("rotated\n"))
+	expectLines(t, lines, "rotated")
+
+	cleanup()
+}
+
+func TestFollowRotateDrainsOldFile(t *testing.T) {
+	cleanup()
+
+	dir := filic.NewDirectory(getTempDirPath())
+	dir.Create()
+	file := filic.NewFile(dir.Join("app.log"))
+	file.Write([]byte("first\n"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lines := startFollow(ctx, file, filic.FollowOptions{Lines: 1, PollInterval: 20 * time.Millisecond})
+	expectLines(t, lines, "first")
+
+	// lines written just before the rotation must not be lost
+	file.Append([]byte("last\n"))
+	if err := os.Rename(file.Path, dir.Join("app.log.1")); err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte("rotated\n"))
+	expectLines(t, lines, "last", "rotated")
+
+	cleanup()
+}
+
+func TestFollowRaw(t *testing.T) {
+	cleanup()
+
+	dir := filic.NewDirectory(getTempDirPath())
+	dir.Create()
+	file := filic.NewFile(dir.Join("data.bin"))
+	file.Write([]byte("ignored"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lines := startFollow(ctx, file, filic.FollowOptions{Raw: true, PollInterval: 20 * time.Millisecond})
+	time.Sleep(100 * time.Millisecond)
+
+	file.Append([]byte("no newline"))
+	expectLines(t, lines, "no newline")
+
+	cleanup()
+}
+
+func TestFollowMissingFile(t *testing.T) {
+	cleanup()
+
+	file := filic.NewFile(getTempDirPath() + "/missing.log")
+	for _, err := range file.Follow(context.Background()) {
+		if !errors.Is(err, filic.ErrNotExist) {
+			t.Errorf("Expected ErrNotExist, got %v", err)
+		}
+	}
+}
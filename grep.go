@@ -0,0 +1,224 @@
+package filic
+
+import (
+	"bufio"
+	"context"
+	"iter"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// GrepOptions configures Directory.Grep.
+type GrepOptions struct {
+	// Literal matches the pattern as plain text instead of a regular
+	// expression.
+	Literal bool
+	// IgnoreCase matches letters regardless of their case.
+	IgnoreCase bool
+	// Context is the number of lines of context reported before and after
+	// every match.
+	Context int
+	// IncludeBinary also searches files that look binary, which are skipped
+	// by default.
+	IncludeBinary bool
+	// Ignore, if set, excludes matching files and directories.
+	Ignore *IgnoreMatcher
+	// Concurrency is the number of files searched at the same time. Zero or
+	// a negative value uses runtime.GOMAXPROCS(0).
+	Concurrency int
+}
+
+// GrepMatch is a line matching the pattern of a Grep.
+type GrepMatch struct {
+	// Path is the path of the file containing the match.
+	Path string `json:"path"`
+	// Line is the 1-based line number of the match.
+	Line int `json:"line"`
+	// Column is the 1-based byte offset of the match within the line.
+	Column int `json:"column"`
+	// Text is the matching line, without its line ending.
+	Text string `json:"text"`
+	// Before and After hold the context lines around the match.
+	Before []string `json:"before,omitempty"`
+	After  []string `json:"after,omitempty"`
+}
+
+// maxGrepLineLen is the longest line Grep can search.
+const maxGrepLineLen = 16 << 20
+
+// Grep searches the contents of every file below the directory for pattern
+// and yields the matching lines as they are found. Files are searched
+// concurrently, so matches from different files are interleaved, but the
+// matches of one file are yielded together and in order. Errors reading a
+// file or a directory are yielded with an empty match and the search
+// continues with the rest of the tree; stop iterating to abort it. If ctx
+// is cancelled the search stops and the context's error is yielded last.
+func (d *Directory) Grep(ctx context.Context, pattern string, opts GrepOptions) iter.Seq2[GrepMatch, error] {
+	return func(yield func(GrepMatch, error) bool) {
+		re, err := compileGrepPattern(pattern, opts)
+		if err != nil {
+			yield(GrepMatch{}, err)
+			return
+		}
+
+		grepCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		type result struct {
+			matches []GrepMatch
+			err     error
+		}
+
+		paths := make(chan string)
+		results := make(chan result)
+
+		report := func(err error) error {
+			select {
+			case results <- result{err: err}:
+				return nil
+			case <-grepCtx.Done():
+				return grepCtx.Err()
+			}
+		}
+
+		go func() {
+			defer close(paths)
+			w := &walker{root: d.Path, ignore: opts.Ignore.forTraversal(), dirErr: report}
+			w.fn = func(entity *Entity) error {
+				if !entity.entry.Type().IsRegular() {
+					return nil
+				}
+				select {
+				case paths <- entity.Path:
+					return nil
+				case <-grepCtx.Done():
+					return grepCtx.Err()
+				}
+			}
+			if err := w.walk(grepCtx, d.Path); err != nil && grepCtx.Err() == nil {
+				report(err)
+			}
+		}()
+
+		var wg sync.WaitGroup
+		for i := 0; i < (ParallelOptions{Concurrency: opts.Concurrency}).concurrency(); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for p := range paths {
+					matches, err := grepFile(grepCtx, p, re, opts)
+					if len(matches) == 0 && err == nil {
+						continue
+					}
+					select {
+					case results <- result{matches: matches, err: err}:
+					case <-grepCtx.Done():
+						return
+					}
+				}
+			}()
+		}
+
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		for r := range results {
+			for _, match := range r.matches {
+				if !yield(match, nil) {
+					return
+				}
+			}
+			if r.err != nil && !yield(GrepMatch{}, r.err) {
+				return
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			yield(GrepMatch{}, err)
+		}
+	}
+}
+
+// compileGrepPattern builds the regular expression described by pattern
+// and opts.
+func compileGrepPattern(pattern string, opts GrepOptions) (*regexp.Regexp, error) {
+	if opts.Literal {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// grepFile searches a single file, returning its matches in order. Binary
+// files are skipped unless opts.IncludeBinary is set.
+func grepFile(ctx context.Context, p string, re *regexp.Regexp, opts GrepOptions) ([]GrepMatch, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return nil, newPathError("grep", p, err)
+	}
+	defer file.Close()
+
+	r := bufio.NewReaderSize(file, 64<<10)
+	if !opts.IncludeBinary {
+		head, _ := r.Peek(binarySniffLen)
+		if isBinary(head) {
+			return nil, nil
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), maxGrepLineLen)
+
+	var matches []GrepMatch
+	var before []string
+	// pending holds the indexes of matches still collecting after-context
+	var pending []int
+
+	for line := 1; scanner.Scan(); line++ {
+		if line%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return matches, err
+			}
+		}
+
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+
+		stillPending := pending[:0]
+		for _, i := range pending {
+			matches[i].After = append(matches[i].After, text)
+			if len(matches[i].After) < opts.Context {
+				stillPending = append(stillPending, i)
+			}
+		}
+		pending = stillPending
+
+		if loc := re.FindStringIndex(text); loc != nil {
+			match := GrepMatch{Path: p, Line: line, Column: loc[0] + 1, Text: text}
+			if len(before) > 0 {
+				match.Before = append([]string(nil), before...)
+			}
+			matches = append(matches, match)
+			if opts.Context > 0 {
+				pending = append(pending, len(matches)-1)
+			}
+		}
+
+		if opts.Context > 0 {
+			before = append(before, text)
+			if len(before) > opts.Context {
+				before = before[1:]
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return matches, newPathError("grep", p, err)
+	}
+	return matches, nil
+}
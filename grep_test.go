@@ -0,0 +1,129 @@
+package filic_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func collectGrep(t *testing.T, dir *filic.Directory, pattern string, opts filic.GrepOptions) []filic.GrepMatch {
+	t.Helper()
+
+	var matches []filic.GrepMatch
+	for match, err := range dir.Grep(context.Background(), pattern, opts) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		matches = append(matches, match)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Path != matches[j].Path {
+			return matches[i].Path < matches[j].Path
+		}
+		return matches[i].Line < matches[j].Line
+	})
+	return matches
+}
+
+func TestGrep(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		"a.go":        "package a\n\nfunc TODO() {}\n// todo: later\n",
+		"sub/b.go":    "package b\n// TODO fix\n",
+		"binary.bin":  "TODO\x00binary",
+		"vendor/v.go": "// TODO vendored\n",
+		"windows.txt": "first\r\nTODO here\r\n",
+	})
+
+	matches := collectGrep(t, dir, "TODO", filic.GrepOptions{
+		Literal: true,
+		Ignore:  filic.NewIgnoreMatcher("vendor/"),
+	})
+
+	if len(matches) != 3 {
+		t.Fatalf("Expected 3 matches, got %+v", matches)
+	}
+
+	first := matches[0]
+	if first.Path != dir.Join("a.go") || first.Line != 3 || first.Column != 6 || first.Text != "func TODO() {}" {
+		t.Errorf("Unexpected match %+v", first)
+	}
+	if matches[2].Text != "TODO here" {
+		t.Errorf("Expected line ending to be stripped, got %q", matches[2].Text)
+	}
+
+	matches = collectGrep(t, dir, "todo", filic.GrepOptions{IgnoreCase: true, IncludeBinary: true, Concurrency: 1})
+	if len(matches) != 6 {
+		t.Errorf("Expected 6 matches, got %+v", matches)
+	}
+
+	cleanup()
+}
+
+func TestGrepContext(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		"lines.txt": "one\ntwo\nthree\nfour\nfive\n",
+	})
+
+	matches := collectGrep(t, dir, `^th`, filic.GrepOptions{Context: 2})
+	if len(matches) != 1 {
+		t.Fatalf("Expected 1 match, got %+v", matches)
+	}
+
+	expectNames(t, []string{"one", "two"}, matches[0].Before)
+	expectNames(t, []string{"four", "five"}, matches[0].After)
+
+	cleanup()
+}
+
+func TestGrepUnreadableDirectory(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		"a/locked/secret.txt": "TODO hidden",
+		"b/open.txt":          "TODO visible",
+	})
+	os.Chmod(dir.Join("a/locked"), 0)
+	defer os.Chmod(dir.Join("a/locked"), 0755)
+	if _, err := os.ReadDir(dir.Join("a/locked")); err == nil {
+		t.Skip("Permissions are not enforced for this user")
+	}
+
+	var matches []filic.GrepMatch
+	var errs []error
+	for match, err := range dir.Grep(context.Background(), "TODO", filic.GrepOptions{Concurrency: 1}) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		matches = append(matches, match)
+	}
+
+	if len(errs) != 1 || !errors.Is(errs[0], os.ErrPermission) {
+		t.Errorf("Expected a permission error, got %v", errs)
+	}
+	if len(matches) != 1 || matches[0].Path != dir.Join("b/open.txt") {
+		t.Errorf("Expected the walk to go on past the directory, got %+v", matches)
+	}
+
+	os.Chmod(dir.Join("a/locked"), 0755)
+	cleanup()
+}
+
+func TestGrepInvalidPattern(t *testing.T) {
+	dir := filic.NewDirectory(getTempDirPath())
+
+	for _, err := range dir.Grep(context.Background(), "(", filic.GrepOptions{}) {
+		if err == nil {
+			t.Error("Expected error for invalid pattern")
+		}
+	}
+}
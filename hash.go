@@ -0,0 +1,31 @@
+package filic
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+)
+
+// Hash returns the hex encoded SHA-256 digest of the file's contents. The
+// file is streamed, so large files are not loaded into memory.
+func (f *File) Hash() (string, error) {
+	return f.HashContext(context.Background())
+}
+
+// HashContext is like Hash but stops and returns the context's error once
+// ctx is cancelled.
+func (f *File) HashContext(ctx context.Context) (string, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return "", newPathError("hash", f.Path, err)
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := copyContext(ctx, h, file); err != nil {
+		return "", newPathError("hash", f.Path, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,321 @@
+package filic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is a single compiled line of an ignore file.
+type ignoreRule struct {
+	// base is the directory, relative to the traversal root, containing the
+	// ignore file the rule came from. Rules only apply below their base.
+	base    string
+	pattern string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// IgnoreMatcher decides which paths are ignored following the semantics of
+// .gitignore files: later rules override earlier ones, "!" negates a
+// pattern, a leading or inner "/" anchors it to the directory of the ignore
+// file, a trailing "/" only matches directories, and "*", "?", "[...]" and
+// "**" work as in git. Once a directory is ignored nothing below it can be
+// re-included.
+//
+// Paths given to a matcher are relative to the root of the traversal it is
+// attached to and use "/" as separator.
+type IgnoreMatcher struct {
+	rules     []ignoreRule
+	fileNames []string
+}
+
+// NewIgnoreMatcher returns a matcher with the given patterns applied at the
+// root of the traversal, as if they were the lines of a root ignore file.
+func NewIgnoreMatcher(patterns ...string) *IgnoreMatcher {
+	m := &IgnoreMatcher{}
+	m.AddPatterns("", patterns...)
+	return m
+}
+
+// AddPatterns adds patterns read from an ignore file located in the
+// directory base, relative to the traversal root. Blank lines and comments
+// starting with "#" are skipped.
+func (m *IgnoreMatcher) AddPatterns(base string, patterns ...string) {
+	base = strings.Trim(path.Clean("/"+base), "/")
+	for _, pattern := range patterns {
+		if rule, ok := compileIgnorePattern(base, pattern); ok {
+			rule.pattern = pattern
+			m.rules = append(m.rules, rule)
+		}
+	}
+}
+
+// AddFile reads the patterns of an ignore file located in the directory
+// base, relative to the traversal root.
+func (m *IgnoreMatcher) AddFile(base string, file *File) error {
+	data, err := file.Read()
+	if err != nil {
+		return err
+	}
+
+	var patterns []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		patterns = append(patterns, scanner.Text())
+	}
+
+	m.AddPatterns(base, patterns...)
+	return nil
+}
+
+// WithIgnoreFiles makes traversals using the matcher load ignore files with
+// the given names, such as ".gitignore", from every directory they enter.
+// Rules from nested ignore files take precedence over those of their
+// parents, like in git. It returns the matcher to allow chaining.
+func (m *IgnoreMatcher) WithIgnoreFiles(names ...string) *IgnoreMatcher {
+	m.fileNames = append(m.fileNames, names...)
+	return m
+}
+
+// LoadIgnoreFiles returns a matcher built from the ignore files with the
+// given names found anywhere in dir, skipping directories that are ignored
+// themselves. Traversals using the matcher don't load ignore files again.
+func LoadIgnoreFiles(dir *Directory, names ...string) (*IgnoreMatcher, error) {
+	m := &IgnoreMatcher{}
+	if err := m.loadDir(dir.Path, "", names); err != nil {
+		return nil, err
+	}
+
+	err := dir.Walk(func(entity *Entity) error {
+		rel := relativePath(dir.Path, entity.Path)
+		isDir := entity.entry.IsDir()
+
+		if m.Match(rel, isDir) {
+			return SkipDir
+		}
+		if isDir {
+			return m.loadDir(entity.Path, rel, names)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// ignoreMatcherJSON is the JSON form of an IgnoreMatcher: its patterns
+// rather than the compiled rules.
+type ignoreMatcherJSON struct {
+	Patterns  []ignorePatternJSON `json:"patterns,omitempty"`
+	FileNames []string            `json:"fileNames,omitempty"`
+}
+
+type ignorePatternJSON struct {
+	Base    string `json:"base,omitempty"`
+	Pattern string `json:"pattern"`
+}
+
+// MarshalJSON encodes the patterns of the matcher and the names of the
+// ignore files it loads, so it can be stored and rebuilt by UnmarshalJSON.
+func (m *IgnoreMatcher) MarshalJSON() ([]byte, error) {
+	j := ignoreMatcherJSON{FileNames: m.fileNames}
+	for _, rule := range m.rules {
+		j.Patterns = append(j.Patterns, ignorePatternJSON{Base: rule.base, Pattern: rule.pattern})
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON rebuilds a matcher encoded by MarshalJSON.
+func (m *IgnoreMatcher) UnmarshalJSON(data []byte) error {
+	var j ignoreMatcherJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*m = IgnoreMatcher{fileNames: j.FileNames}
+	for _, p := range j.Patterns {
+		m.AddPatterns(p.Base, p.Pattern)
+	}
+	return nil
+}
+
+// loadDir adds the rules of the ignore files called names in the directory
+// at dirPath, whose path relative to the traversal root is rel. Missing
+// ignore files are skipped.
+func (m *IgnoreMatcher) loadDir(dirPath, rel string, names []string) error {
+	for _, name := range names {
+		err := m.AddFile(rel, NewFile(path.Join(dirPath, name)))
+		if err != nil && !errors.Is(err, ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// forTraversal returns the matcher to use for one traversal. When the
+// matcher loads nested ignore files, a copy is returned so that rules found
+// during the traversal don't leak into the original.
+func (m *IgnoreMatcher) forTraversal() *IgnoreMatcher {
+	if m == nil || len(m.fileNames) == 0 {
+		return m
+	}
+	return &IgnoreMatcher{
+		rules:     append([]ignoreRule(nil), m.rules...),
+		fileNames: m.fileNames,
+	}
+}
+
+// Match reports whether the path rel, relative to the traversal root, is
+// ignored. isDir tells whether the path is a directory. A path is also
+// ignored when one of its parent directories is.
+func (m *IgnoreMatcher) Match(rel string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+
+	rel = strings.Trim(path.Clean("/"+rel), "/")
+	if rel == "" {
+		return false
+	}
+
+	for i := 0; i < len(rel); i++ {
+		if rel[i] == '/' && m.match(rel[:i], true) {
+			return true
+		}
+	}
+
+	return m.match(rel, isDir)
+}
+
+// match applies the rules to rel without considering its parents.
+func (m *IgnoreMatcher) match(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		target := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			target = rel[len(rule.base)+1:]
+		}
+
+		if rule.re.MatchString(target) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// compileIgnorePattern compiles one line of an ignore file. It returns false
+// for blank lines, comments and patterns that can't be compiled.
+func compileIgnorePattern(base, pattern string) (ignoreRule, bool) {
+	pattern = trimIgnoreTrailingSpace(strings.TrimSuffix(pattern, "\r"))
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+
+	if strings.HasPrefix(pattern, "!") {
+		rule.negate = true
+		pattern = pattern[1:]
+	}
+
+	if strings.HasSuffix(pattern, "/") && !strings.HasSuffix(pattern, "\\/") {
+		rule.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+
+	if pattern == "" {
+		return ignoreRule{}, false
+	}
+
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(?:.*/)?")
+	}
+	expr.WriteString(ignoreGlobToRegexp(pattern))
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.re = re
+
+	return rule, true
+}
+
+// trimIgnoreTrailingSpace removes trailing spaces unless they are escaped
+// with a backslash.
+func trimIgnoreTrailingSpace(pattern string) string {
+	for strings.HasSuffix(pattern, " ") && !strings.HasSuffix(pattern, "\\ ") {
+		pattern = pattern[:len(pattern)-1]
+	}
+	return pattern
+}
+
+// ignoreGlobToRegexp translates a gitignore glob to a regular expression.
+func ignoreGlobToRegexp(glob string) string {
+	var expr strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+
+		switch {
+		case strings.HasPrefix(glob[i:], "**/") && (i == 0 || glob[i-1] == '/'):
+			// "**/" matches zero or more directories
+			expr.WriteString("(?:.*/)?")
+			i += 2
+
+		case strings.HasPrefix(glob[i:], "**") && i+2 == len(glob) && (i == 0 || glob[i-1] == '/'):
+			// a trailing "**" matches everything inside
+			expr.WriteString(".*")
+			i++
+
+		case c == '*':
+			expr.WriteString("[^/]*")
+
+		case c == '?':
+			expr.WriteString("[^/]")
+
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				expr.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, "\\", "\\\\") + "]")
+			i += end + 1
+
+		case c == '\\' && i+1 < len(glob):
+			i++
+			expr.WriteString(regexp.QuoteMeta(string(glob[i])))
+
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return expr.String()
+}
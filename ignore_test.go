@@ -0,0 +1,142 @@
+package filic_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func TestIgnoreMatcherPatterns(t *testing.T) {
+	m := filic.NewIgnoreMatcher(
+		"# comment",
+		"*.log",
+		"!keep.log",
+		"/root-only.txt",
+		"build/",
+		"docs/**/*.tmp",
+		"cache?",
+		"\\#literal",
+	)
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"app.log", false, true},
+		{"nested/app.log", false, true},
+		{"keep.log", false, false},
+		{"nested/keep.log", false, false},
+		{"root-only.txt", false, true},
+		{"nested/root-only.txt", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"build/output.bin", false, true},
+		{"nested/build/output.bin", false, true},
+		{"docs/a.tmp", false, true},
+		{"docs/x/y/a.tmp", false, true},
+		{"other/a.tmp", false, false},
+		{"cache1", false, true},
+		{"cache12", false, false},
+		{"#literal", false, true},
+		{"main.go", false, false},
+	}
+
+	for _, test := range tests {
+		if got := m.Match(test.path, test.isDir); got != test.ignored {
+			t.Errorf("Match(%q, %v) = %v, expected %v", test.path, test.isDir, got, test.ignored)
+		}
+	}
+}
+
+func TestIgnoreMatcherNoReincludeInIgnoredDir(t *testing.T) {
+	m := filic.NewIgnoreMatcher("vendor/", "!vendor/keep.go")
+
+	if !m.Match("vendor/keep.go", false) {
+		t.Error("Files inside an ignored directory can't be re-included")
+	}
+}
+
+func TestWalkWithNestedIgnoreFiles(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		".gitignore":         "*.log\nout/\n",
+		"main.go":            "",
+		"debug.log":          "",
+		"out/bin":            "",
+		"sub/.gitignore":     "!important.log\n/local.txt\n",
+		"sub/important.log":  "",
+		"sub/other.log":      "",
+		"sub/local.txt":      "",
+		"sub/deep/local.txt": "",
+	})
+
+	m := filic.NewIgnoreMatcher(".gitignore").WithIgnoreFiles(".gitignore")
+
+	var visited []*filic.Entity
+	err := dir.WalkWithOptions(context.Background(), filic.WalkOptions{Ignore: m}, func(entity *filic.Entity) error {
+		visited = append(visited, entity)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectNames(t, []string{
+		"main.go",
+		"sub",
+		"sub/deep",
+		"sub/deep/local.txt",
+		"sub/important.log",
+	}, relNames(dir, visited))
+
+	// the same result through a pre-loaded matcher and a query
+	loaded, err := filic.LoadIgnoreFiles(dir, ".gitignore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded.AddPatterns("", ".gitignore")
+
+	files, err := dir.Find().Files().Ignore(loaded).Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNames(t, []string{"main.go", "sub/deep/local.txt", "sub/important.log"}, relNames(dir, files))
+
+	cleanup()
+}
+
+func TestCopyAndListWithIgnore(t *testing.T) {
+	cleanup()
+
+	createTree(t, map[string]string{
+		"src/keep.txt":       "k",
+		"src/skip.log":       "s",
+		"src/node_modules/x": "x",
+	})
+
+	src := filic.NewDirectory(getTempDirPath() + "/src")
+	dest := filic.NewDirectory(getTempDirPath() + "/dest")
+	m := filic.NewIgnoreMatcher("*.log", "node_modules/")
+
+	err := src.CopyToWithOptions(context.Background(), dest, filic.CopyOptions{Ignore: m})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := dest.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNames(t, []string{"keep.txt"}, names)
+
+	entities, err := src.ListWithOptions(context.Background(), filic.ListOptions{Ignore: m, Sort: filic.SortByName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNames(t, []string{"keep.txt"}, relNames(src, entities))
+
+	cleanup()
+}
@@ -0,0 +1,294 @@
+package filic
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// kvHeaderLen is the size of the header preceding every value: its expiry
+// time in Unix nanoseconds, big endian, or zero if it never expires.
+const kvHeaderLen = 8
+
+// maxKVNameLen is the longest encoded key most file systems accept as a
+// file name.
+const maxKVNameLen = 255
+
+// KV is a persistent key-value store backed by a Directory, with one file
+// per key. Keys can be any non-empty string: they are escaped into file
+// names, so slashes, dots and unicode are safe and cannot escape the
+// directory. Values are replaced atomically.
+type KV struct {
+	Dir *Directory
+}
+
+// NewKV returns the key-value store kept in dir. The directory is created
+// when the first value is set.
+func NewKV(dir *Directory) *KV {
+	return &KV{Dir: dir}
+}
+
+// encodeKVKey escapes key into a file name. Lowercase letters, digits, '-'
+// and '_' are kept and every other byte becomes %XX, so keys differing only
+// in case stay distinct on case-insensitive file systems and the prefix of
+// a key always encodes to the prefix of its file name.
+func encodeKVKey(key string) string {
+	const hexDigits = "0123456789ABCDEF"
+
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if isKVKeyByte(c) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hexDigits[c>>4])
+		b.WriteByte(hexDigits[c&0xf])
+	}
+	return b.String()
+}
+
+// decodeKVKey reverses encodeKVKey, reporting false for names that are not
+// encoded keys, such as the temporary files of values being written. Only
+// names encodeKVKey can produce are accepted, so every key has a single
+// file.
+func decodeKVKey(name string) (string, bool) {
+	if name == "" {
+		return "", false
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if isKVKeyByte(c) {
+			b.WriteByte(c)
+			continue
+		}
+		if c != '%' || i+2 >= len(name) {
+			return "", false
+		}
+
+		c = 0
+		for _, h := range name[i+1 : i+3] {
+			switch {
+			case h >= '0' && h <= '9':
+				c = c<<4 | byte(h-'0')
+			case h >= 'A' && h <= 'F':
+				c = c<<4 | byte(h-'A'+10)
+			default:
+				return "", false
+			}
+		}
+		if isKVKeyByte(c) {
+			return "", false
+		}
+		b.WriteByte(c)
+		i += 2
+	}
+	return b.String(), true
+}
+
+// isKVKeyByte reports whether encodeKVKey keeps c as is.
+func isKVKeyByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_'
+}
+
+// keyPath returns the path of the file holding key.
+func (kv *KV) keyPath(op, key string) (string, error) {
+	name := encodeKVKey(key)
+	if key == "" || len(name) > maxKVNameLen {
+		return "", &PathError{Op: op, Path: kv.Dir.Path, Err: ErrInvalidKey}
+	}
+	return kv.Dir.Join(name), nil
+}
+
+// Set stores value under key, replacing any previous value.
+func (kv *KV) Set(key string, value []byte) error {
+	return kv.SetWithTTL(key, value, 0)
+}
+
+// SetWithTTL stores value under key so that it expires after ttl. A ttl of
+// zero or less never expires.
+func (kv *KV) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	p, err := kv.keyPath("set", key)
+	if err != nil {
+		return err
+	}
+
+	var header [kvHeaderLen]byte
+	if ttl > 0 {
+		binary.BigEndian.PutUint64(header[:], uint64(time.Now().Add(ttl).UnixNano()))
+	}
+
+	if err := os.MkdirAll(kv.Dir.Path, 0755); err != nil {
+		return newPathError("set", kv.Dir.Path, err)
+	}
+
+	err = atomicWriteFile(p, 0644, func(w io.Writer) error {
+		if _, err := w.Write(header[:]); err != nil {
+			return err
+		}
+		_, err := w.Write(value)
+		return err
+	})
+	return newPathError("set", p, err)
+}
+
+// Get returns the value stored under key. A missing or expired key returns
+// an error matching ErrNotExist.
+func (kv *KV) Get(key string) ([]byte, error) {
+	p, err := kv.keyPath("get", key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, newPathError("get", p, err)
+	}
+
+	value, expired, err := parseKVValue(data)
+	if err != nil {
+		return nil, newPathError("get", p, err)
+	}
+	if expired {
+		return nil, &PathError{Op: "get", Path: p, Err: ErrNotExist}
+	}
+	return value, nil
+}
+
+// parseKVValue splits a stored value from its header and reports whether it
+// has expired.
+func parseKVValue(data []byte) ([]byte, bool, error) {
+	if len(data) < kvHeaderLen {
+		return nil, false, errors.New("corrupt value")
+	}
+
+	expiry := int64(binary.BigEndian.Uint64(data[:kvHeaderLen]))
+	expired := expiry != 0 && time.Now().UnixNano() >= expiry
+	return data[kvHeaderLen:], expired, nil
+}
+
+// Delete removes key from the store. Deleting a missing key is not an error.
+func (kv *KV) Delete(key string) error {
+	p, err := kv.keyPath("delete", key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return newPathError("delete", p, err)
+	}
+	return nil
+}
+
+// Keys returns the keys starting with prefix that have not expired, sorted.
+// An empty prefix returns every key.
+func (kv *KV) Keys(prefix string) ([]string, error) {
+	var keys []string
+	err := kv.scan(prefix, func(key, p string, expired bool) {
+		if !expired {
+			keys = append(keys, key)
+		}
+	})
+	sort.Strings(keys)
+	return keys, err
+}
+
+// Purge removes the expired keys from the store and returns how many were
+// removed. Expired keys are never returned by Get or Keys, but their files
+// remain until purged.
+func (kv *KV) Purge() (int, error) {
+	removed := 0
+	var errs []error
+
+	err := kv.scan("", func(key, p string, expired bool) {
+		if !expired {
+			return
+		}
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, newPathError("purge", p, err))
+			return
+		}
+		removed++
+	})
+	return removed, errors.Join(append([]error{err}, errs...)...)
+}
+
+// scan calls fn for every key starting with prefix. Only the headers of
+// the values are read.
+func (kv *KV) scan(prefix string, fn func(key, p string, expired bool)) error {
+	entries, err := os.ReadDir(kv.Dir.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return newPathError("keys", kv.Dir.Path, err)
+	}
+
+	encodedPrefix := encodeKVKey(prefix)
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !strings.HasPrefix(name, encodedPrefix) {
+			continue
+		}
+		key, ok := decodeKVKey(name)
+		if !ok {
+			continue
+		}
+
+		p := kv.Dir.Join(name)
+		expired, err := kvExpired(p)
+		if err != nil {
+			// removed or replaced meanwhile
+			continue
+		}
+		fn(key, p, expired)
+	}
+	return nil
+}
+
+// kvExpired reads the header of the value stored at p and reports whether
+// it has expired.
+func kvExpired(p string) (bool, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	var header [kvHeaderLen]byte
+	if _, err := io.ReadFull(file, header[:]); err != nil {
+		return false, err
+	}
+	_, expired, err := parseKVValue(header[:])
+	return expired, err
+}
+
+// SetJSON stores the JSON encoding of v under key.
+func (kv *KV) SetJSON(key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return &PathError{Op: "set", Path: kv.Dir.Path, Err: err}
+	}
+	return kv.Set(key, data)
+}
+
+// GetJSON decodes the JSON value stored under key into v.
+func (kv *KV) GetJSON(key string, v any) error {
+	data, err := kv.Get(key)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		p, _ := kv.keyPath("get", key)
+		return &PathError{Op: "get", Path: p, Err: err}
+	}
+	return nil
+}
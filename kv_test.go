@@ -0,0 +1,129 @@
+package filic_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func TestKVSetGetDelete(t *testing.T) {
+	cleanup()
+
+	kv := filic.NewKV(filic.NewDirectory(getTempDirPath()))
+
+	keys := []string{"user/42", "../../etc/passwd", "Ünïcode key", "User/42", ".", "a%20b"}
+	for _, key := range keys {
+		if err := kv.Set(key, []byte("value of "+key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, key := range keys {
+		value, err := kv.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(value) != "value of "+key {
+			t.Errorf("Expected %q for key %q, got %q", "value of "+key, key, value)
+		}
+	}
+
+	entries, _ := os.ReadDir(getTempDirPath())
+	if len(entries) != len(keys) {
+		t.Errorf("Expected %v files in the store directory, got %v", len(keys), len(entries))
+	}
+	if _, err := os.Stat(getTempDirPath() + "/../etc"); err == nil {
+		t.Error("Expected keys not to escape the store directory")
+	}
+
+	kv.Delete("user/42")
+	if _, err := kv.Get("user/42"); !errors.Is(err, filic.ErrNotExist) {
+		t.Errorf("Expected ErrNotExist after Delete, got %v", err)
+	}
+	if err := kv.Delete("user/42"); err != nil {
+		t.Errorf("Expected deleting a missing key to succeed, got %v", err)
+	}
+
+	if err := kv.Set("", nil); !errors.Is(err, filic.ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey for an empty key, got %v", err)
+	}
+	if err := kv.Set(strings.Repeat("/", 100), nil); !errors.Is(err, filic.ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey for a too long key, got %v", err)
+	}
+
+	cleanup()
+}
+
+func TestKVKeysAndTTL(t *testing.T) {
+	cleanup()
+
+	kv := filic.NewKV(filic.NewDirectory(getTempDirPath()))
+
+	kv.Set("session/b", []byte("b"))
+	kv.Set("session/a", []byte("a"))
+	kv.SetWithTTL("session/expiring", []byte("x"), 50*time.Millisecond)
+	kv.Set("sessions", []byte("other"))
+	kv.Set("config", []byte("c"))
+
+	keys, err := kv.Keys("session/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNames(t, []string{"session/a", "session/b", "session/expiring"}, keys)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := kv.Get("session/expiring"); !errors.Is(err, filic.ErrNotExist) {
+		t.Errorf("Expected ErrNotExist for an expired key, got %v", err)
+	}
+
+	keys, _ = kv.Keys("session/")
+	expectNames(t, []string{"session/a", "session/b"}, keys)
+
+	removed, err := kv.Purge()
+	if err != nil || removed != 1 {
+		t.Errorf("Expected 1 purged key, got %v (%v)", removed, err)
+	}
+
+	keys, _ = kv.Keys("")
+	expectNames(t, []string{"config", "session/a", "session/b", "sessions"}, keys)
+
+	// files that are not encoded keys, like a value being written, are
+	// not keys
+	for _, name := range []string{".config.123.tmp", "Config", "%63onfig", "conf.bak"} {
+		os.WriteFile(kv.Dir.Join(name), make([]byte, 8), 0644)
+	}
+	keys, _ = kv.Keys("")
+	expectNames(t, []string{"config", "session/a", "session/b", "sessions"}, keys)
+
+	cleanup()
+}
+
+func TestKVJSON(t *testing.T) {
+	cleanup()
+
+	type user struct {
+		Name  string
+		Admin bool
+	}
+
+	kv := filic.NewKV(filic.NewDirectory(getTempDirPath()))
+
+	if err := kv.SetJSON("user/1", user{Name: "ada", Admin: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got user
+	if err := kv.GetJSON("user/1", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "ada" || !got.Admin {
+		t.Errorf("Unexpected decoded value %+v", got)
+	}
+
+	cleanup()
+}
@@ -0,0 +1,168 @@
+package filic
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"os"
+	"sort"
+)
+
+// SortBy selects the order of entities returned by ListWithOptions.
+type SortBy int
+
+const (
+	// SortNone keeps the order in which the operating system returns entries.
+	SortNone SortBy = iota
+	// SortByName orders entities by name.
+	SortByName
+	// SortBySize orders entities by size, then by name.
+	SortBySize
+	// SortByModTime orders entities by modification time, then by name.
+	SortByModTime
+)
+
+// ListOptions configures ListWithOptions.
+type ListOptions struct {
+	// Sort selects the order of the returned entities.
+	Sort SortBy
+	// Reverse reverses the sort order.
+	Reverse bool
+	// BatchSize is the number of entries read from the directory at a time.
+	// Zero uses a default suited to large directories.
+	BatchSize int
+	// Ignore, if set, excludes matching entities from the listing.
+	Ignore *IgnoreMatcher
+}
+
+func (o ListOptions) batchSize() int {
+	if o.BatchSize <= 0 {
+		return readDirBatch
+	}
+	return o.BatchSize
+}
+
+// Entries streams the entries of the directory in the order the operating
+// system returns them, reading at most batchSize entries at a time. Unlike
+// the List methods it never holds the whole directory in memory, which
+// makes it suitable for directories with hundreds of thousands of entries.
+// A batchSize of zero or less uses a default. If reading fails the error is
+// yielded with a nil entity and the iteration ends.
+func (d *Directory) Entries(ctx context.Context, batchSize int) iter.Seq2[*Entity, error] {
+	return func(yield func(*Entity, error) bool) {
+		if batchSize <= 0 {
+			batchSize = readDirBatch
+		}
+
+		dir, err := os.Open(d.Path)
+		if err != nil {
+			yield(nil, newPathError("list", d.Path, err))
+			return
+		}
+		defer dir.Close()
+
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			batch, err := dir.ReadDir(batchSize)
+			for _, entry := range batch {
+				if !yield(newListedEntity(d.Path, entry), nil) {
+					return
+				}
+			}
+
+			if errors.Is(err, io.EOF) || (err == nil && len(batch) == 0) {
+				return
+			}
+			if err != nil {
+				yield(nil, newPathError("list", d.Path, err))
+				return
+			}
+		}
+	}
+}
+
+// ListWithOptions returns the entities of the directory ordered as requested
+// by opts. Sorting by size or modification time reads the FileInfo of every
+// entity, which is then cached in the returned entities.
+func (d *Directory) ListWithOptions(ctx context.Context, opts ListOptions) ([]*Entity, error) {
+	ignore := opts.Ignore.forTraversal()
+	if ignore != nil {
+		if err := ignore.loadDir(d.Path, "", ignore.fileNames); err != nil {
+			return nil, err
+		}
+	}
+
+	var entities []*Entity
+	for entity, err := range d.Entries(ctx, opts.batchSize()) {
+		if err != nil {
+			return nil, err
+		}
+		if ignore.Match(entity.Name(), entity.entry.IsDir()) {
+			continue
+		}
+		entities = append(entities, entity)
+	}
+
+	if err := sortEntities(entities, opts.Sort); err != nil {
+		return nil, err
+	}
+
+	if opts.Reverse {
+		for i, j := 0, len(entities)-1; i < j; i, j = i+1, j-1 {
+			entities[i], entities[j] = entities[j], entities[i]
+		}
+	}
+
+	return entities, nil
+}
+
+// sortEntities sorts entities in place by the given key, breaking ties by name.
+func sortEntities(entities []*Entity, by SortBy) error {
+	if by == SortNone {
+		return nil
+	}
+
+	infos := make([]os.FileInfo, len(entities))
+	if by == SortBySize || by == SortByModTime {
+		for i, entity := range entities {
+			info, err := entity.Info()
+			if err != nil {
+				return err
+			}
+			infos[i] = info
+		}
+	}
+
+	indexes := make([]int, len(entities))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	sort.SliceStable(indexes, func(a, b int) bool {
+		i, j := indexes[a], indexes[b]
+		switch by {
+		case SortBySize:
+			if infos[i].Size() != infos[j].Size() {
+				return infos[i].Size() < infos[j].Size()
+			}
+		case SortByModTime:
+			if !infos[i].ModTime().Equal(infos[j].ModTime()) {
+				return infos[i].ModTime().Before(infos[j].ModTime())
+			}
+		}
+		return entities[i].Name() < entities[j].Name()
+	})
+
+	sorted := make([]*Entity, len(entities))
+	for a, i := range indexes {
+		sorted[a] = entities[i]
+	}
+	copy(entities, sorted)
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package filic_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func TestEntriesBatched(t *testing.T) {
+	cleanup()
+
+	files := map[string]string{}
+	for i := 0; i < 25; i++ {
+		files[fmt.Sprintf("file%02d.txt", i)] = "x"
+	}
+	dir := createTree(t, files)
+
+	count := 0
+	for entity, err := range dir.Entries(context.Background(), 4) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := files[entity.Name()]; !ok {
+			t.Errorf("Unexpected entity: %s", entity.Name())
+		}
+		count++
+	}
+
+	if count != 25 {
+		t.Errorf("Expected 25 entities, got %d", count)
+	}
+
+	cleanup()
+}
+
+func TestEntriesNonExistentDirectory(t *testing.T) {
+	cleanup()
+
+	dir := filic.NewDirectory(getTempDirPath())
+
+	for entity, err := range dir.Entries(context.Background(), 0) {
+		if err == nil {
+			t.Errorf("Expected error, got entity %v", entity.Path)
+		}
+	}
+}
+
+func TestListWithOptionsSort(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		"b.txt": "123",
+		"a.txt": "12345",
+		"c.txt": "1",
+	})
+
+	now := time.Now()
+	os.Chtimes(dir.Join("a.txt"), now, now.Add(-2*time.Hour))
+	os.Chtimes(dir.Join("b.txt"), now, now.Add(-1*time.Hour))
+	os.Chtimes(dir.Join("c.txt"), now, now.Add(-3*time.Hour))
+
+	tests := []struct {
+		opts     filic.ListOptions
+		expected []string
+	}{
+		{filic.ListOptions{Sort: filic.SortByName}, []string{"a.txt", "b.txt", "c.txt"}},
+		{filic.ListOptions{Sort: filic.SortByName, Reverse: true}, []string{"c.txt", "b.txt", "a.txt"}},
+		{filic.ListOptions{Sort: filic.SortBySize}, []string{"c.txt", "b.txt", "a.txt"}},
+		{filic.ListOptions{Sort: filic.SortByModTime}, []string{"c.txt", "a.txt", "b.txt"}},
+	}
+
+	for _, test := range tests {
+		entities, err := dir.ListWithOptions(context.Background(), test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(entities) != len(test.expected) {
+			t.Fatalf("Expected %d entities, got %d", len(test.expected), len(entities))
+		}
+
+		for i, entity := range entities {
+			if entity.Name() != test.expected[i] {
+				t.Errorf("Sort %v: expected %v at %d, got %v", test.opts, test.expected[i], i, entity.Name())
+			}
+		}
+	}
+
+	cleanup()
+}
+
+func TestListedEntityCachesInfo(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{"a.txt": "abc"})
+	os.MkdirAll(dir.Join("sub"), 0755)
+
+	entities, err := dir.ListAsEntities()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range entities {
+		entity := &entities[i]
+
+		info, err := entity.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		isDir, err := entity.IsDirectory()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isDir != info.IsDir() {
+			t.Errorf("IsDirectory and Info disagree for %v", entity.Path)
+		}
+	}
+
+	// the info is cached, so removing the file doesn't affect it
+	os.Remove(dir.Join("a.txt"))
+	for i := range entities {
+		entity := &entities[i]
+		if entity.Name() != "a.txt" {
+			continue
+		}
+		info, err := entity.Info()
+		if err != nil {
+			t.Error(err)
+		} else if info.Size() != 3 {
+			t.Errorf("Expected cached size 3, got %d", info.Size())
+		}
+	}
+
+	cleanup()
+}
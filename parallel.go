@@ -0,0 +1,268 @@
+package filic
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"iter"
+	"path"
+	"runtime"
+	"sync"
+)
+
+// ParallelOptions configures the concurrent operations of filic.
+type ParallelOptions struct {
+	// Concurrency is the maximum number of operations running at the same
+	// time. Zero or a negative value uses runtime.GOMAXPROCS(0).
+	Concurrency int
+	// Ignore, if set, excludes matching entities from WalkParallel and
+	// CopyToParallel. The other operations work on the entities they are
+	// given and don't use it.
+	Ignore *IgnoreMatcher
+}
+
+func (o ParallelOptions) concurrency() int {
+	if o.Concurrency <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return o.Concurrency
+}
+
+// forEachParallel calls fn for every index in [0, n) using at most workers
+// goroutines. Errors are joined in index order, so the result doesn't depend
+// on scheduling. Once ctx is cancelled no new calls are started and the
+// context's error is added to the result.
+func forEachParallel(ctx context.Context, workers, n int, fn func(i int) error) error {
+	errs := make([]error, n+1)
+	sem := make(chan struct{}, workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = fn(i)
+		}(i)
+	}
+	wg.Wait()
+
+	errs[n] = ctx.Err()
+	return errors.Join(errs...)
+}
+
+// WalkParallel walks the tree below d like Walk, but reads directories
+// concurrently using opts.Concurrency workers pulling from a queue of
+// directories to read. Entities are yielded as soon as they are found, so
+// the order is not deterministic. Entities matched by opts.Ignore are left
+// out, and ignored directories are not read. Errors reading a directory
+// are yielded with a nil entity and the walk continues with the rest of the
+// tree; stop iterating to abort it. If ctx is cancelled the walk stops and
+// the context's error is yielded last.
+func (d *Directory) WalkParallel(ctx context.Context, opts ParallelOptions) iter.Seq2[*Entity, error] {
+	return func(yield func(*Entity, error) bool) {
+		walkCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		type result struct {
+			entity *Entity
+			err    error
+		}
+
+		results := make(chan result)
+		send := func(r result) bool {
+			select {
+			case results <- r:
+				return true
+			case <-walkCtx.Done():
+				return false
+			}
+		}
+
+		// queue holds the directories left to read, and pending counts
+		// them along with the ones being read: the walk is over when it
+		// drops to zero
+		var mu sync.Mutex
+		cond := sync.NewCond(&mu)
+		queue := []string{d.Path}
+		pending := 1
+
+		stop := context.AfterFunc(walkCtx, func() {
+			mu.Lock()
+			cond.Broadcast()
+			mu.Unlock()
+		})
+		defer stop()
+
+		next := func() (string, bool) {
+			mu.Lock()
+			defer mu.Unlock()
+			for len(queue) == 0 && pending > 0 && walkCtx.Err() == nil {
+				cond.Wait()
+			}
+			if len(queue) == 0 || walkCtx.Err() != nil {
+				return "", false
+			}
+			dir := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			return dir, true
+		}
+
+		finish := func(subdirs []string) {
+			mu.Lock()
+			defer mu.Unlock()
+			queue = append(queue, subdirs...)
+			pending += len(subdirs) - 1
+			cond.Broadcast()
+		}
+
+		// rules loaded from nested ignore files are scoped to their
+		// directory, so only the matcher itself needs guarding
+		ignore := opts.Ignore.forTraversal()
+		var ignoreMu sync.RWMutex
+
+		visit := func(dir string) []string {
+			entries, err := readDirContext(walkCtx, dir)
+			if err != nil {
+				if walkCtx.Err() == nil {
+					send(result{err: newPathError("walk", dir, err)})
+				}
+				return nil
+			}
+
+			if ignore != nil && len(ignore.fileNames) > 0 {
+				ignoreMu.Lock()
+				err := ignore.loadDir(dir, relativePath(d.Path, dir), ignore.fileNames)
+				ignoreMu.Unlock()
+				if err != nil && !send(result{err: err}) {
+					return nil
+				}
+			}
+
+			var subdirs []string
+			for _, entry := range entries {
+				entity := newListedEntity(dir, entry)
+
+				ignoreMu.RLock()
+				ignored := ignore.Match(relativePath(d.Path, entity.Path), entry.IsDir())
+				ignoreMu.RUnlock()
+				if ignored {
+					continue
+				}
+
+				if !send(result{entity: entity}) {
+					return nil
+				}
+				if entry.IsDir() {
+					subdirs = append(subdirs, entity.Path)
+				}
+			}
+			return subdirs
+		}
+
+		var wg sync.WaitGroup
+		for range opts.concurrency() {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					dir, ok := next()
+					if !ok {
+						return
+					}
+					finish(visit(dir))
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		for r := range results {
+			if !yield(r.entity, r.err) {
+				return
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// CopyToParallel is like CopyToContext but copies files concurrently using
+// at most opts.Concurrency workers, leaving out the entities matched by
+// opts.Ignore. Directories and symbolic links are created first; errors
+// copying individual files don't stop the others and are joined in path
+// order.
+func (d *Directory) CopyToParallel(ctx context.Context, dest *Directory, opts ParallelOptions) error {
+	if err := dest.Create(); err != nil {
+		return err
+	}
+
+	var sources, targets []string
+	err := d.WalkWithOptions(ctx, WalkOptions{Ignore: opts.Ignore}, func(entity *Entity) error {
+		target := path.Join(dest.Path, relativePath(d.Path, entity.Path))
+
+		info, err := entity.lstat()
+		if err != nil {
+			return newPathError("copy", entity.Path, err)
+		}
+
+		if info.Mode().IsRegular() {
+			sources = append(sources, entity.Path)
+			targets = append(targets, target)
+			return nil
+		}
+
+		return copyEntity(ctx, entity, target)
+	})
+	if err != nil {
+		return err
+	}
+
+	return forEachParallel(ctx, opts.concurrency(), len(sources), func(i int) error {
+		return NewFile(sources[i]).CopyToContext(ctx, NewFile(targets[i]))
+	})
+}
+
+// DeleteAll deletes the given entities concurrently using at most
+// opts.Concurrency workers. Directories are deleted with their contents.
+// All entities are attempted; errors are joined in the order of entities.
+func DeleteAll(ctx context.Context, entities []*Entity, opts ParallelOptions) error {
+	return forEachParallel(ctx, opts.concurrency(), len(entities), func(i int) error {
+		return entities[i].Delete()
+	})
+}
+
+// ChmodAll changes the mode of the given entities concurrently using at
+// most opts.Concurrency workers. All entities are attempted; errors are
+// joined in the order of entities.
+func ChmodAll(ctx context.Context, entities []*Entity, mode fs.FileMode, opts ParallelOptions) error {
+	return forEachParallel(ctx, opts.concurrency(), len(entities), func(i int) error {
+		return entities[i].Chmod(mode)
+	})
+}
+
+// HashAll computes the SHA-256 digests of the given files concurrently
+// using at most opts.Concurrency workers. The returned digests are in the
+// same order as files; the digest of a file that failed is left empty and
+// its error is joined into the returned error in the order of files.
+func HashAll(ctx context.Context, files []*File, opts ParallelOptions) ([]string, error) {
+	digests := make([]string, len(files))
+	err := forEachParallel(ctx, opts.concurrency(), len(files), func(i int) error {
+		digest, err := files[i].HashContext(ctx)
+		digests[i] = digest
+		return err
+	})
+	return digests, err
+}
@@ -0,0 +1,211 @@
+package filic_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func TestWalkParallel(t *testing.T) {
+	cleanup()
+
+	files := map[string]string{}
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 5; j++ {
+			files[fmt.Sprintf("d%d/f%d.txt", i, j)] = "x"
+		}
+	}
+	dir := createTree(t, files)
+
+	found := map[string]bool{}
+	for entity, err := range dir.WalkParallel(context.Background(), filic.ParallelOptions{Concurrency: 3}) {
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		found[strings.TrimPrefix(entity.Path, dir.Path+"/")] = true
+	}
+
+	// 25 files and 5 directories
+	if len(found) != 30 {
+		t.Errorf("Expected 30 entities, got %d", len(found))
+	}
+	for name := range files {
+		if !found[name] {
+			t.Errorf("Expected entity not found: %s", name)
+		}
+	}
+
+	cleanup()
+}
+
+func TestParallelIgnore(t *testing.T) {
+	cleanup()
+
+	createTree(t, map[string]string{
+		"project/src/main.go":          "package main",
+		"project/src/gen/.gitignore":   "*.pb.go\n",
+		"project/src/gen/api.pb.go":    "generated",
+		"project/src/gen/api.go":       "package gen",
+		"project/node_modules/x/a.js":  "x",
+		"project/build/out.bin":        "bin",
+		"project/other/gen/keep.pb.go": "kept",
+		"project/.gitignore":           "build/\n",
+	})
+	dir := filic.NewDirectory(path.Join(getTempDirPath(), "project"))
+
+	ignore := filic.NewIgnoreMatcher("node_modules/").WithIgnoreFiles(".gitignore")
+	opts := filic.ParallelOptions{Concurrency: 4, Ignore: ignore}
+
+	var names []string
+	for entity, err := range dir.WalkParallel(context.Background(), opts) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entity.Name() != ".gitignore" {
+			names = append(names, strings.TrimPrefix(entity.Path, dir.Path+"/"))
+		}
+	}
+	sort.Strings(names)
+	expected := []string{"other", "other/gen", "other/gen/keep.pb.go", "src", "src/gen", "src/gen/api.go", "src/main.go"}
+	expectNames(t, expected, names)
+
+	dest := filic.NewDirectory(path.Join(getTempDirPath(), "dest"))
+	if err := dir.CopyToParallel(context.Background(), dest, opts); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"node_modules", "build", "src/gen/api.pb.go"} {
+		if filic.NewEntity(dest.Join(name)).Exists() {
+			t.Errorf("Expected %s not to be copied", name)
+		}
+	}
+	if !filic.NewEntity(dest.Join("src/gen/api.go")).Exists() {
+		t.Error("Expected src/gen/api.go to be copied")
+	}
+
+	cleanup()
+}
+
+func TestWalkParallelBreak(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{"a/1": "", "b/2": "", "c/3": ""})
+
+	count := 0
+	for range dir.WalkParallel(context.Background(), filic.ParallelOptions{}) {
+		count++
+		break
+	}
+
+	if count != 1 {
+		t.Errorf("Expected to stop after 1 entity, got %d", count)
+	}
+
+	cleanup()
+}
+
+func TestCopyToParallel(t *testing.T) {
+	cleanup()
+
+	createTree(t, map[string]string{
+		"src/a.txt":       "a",
+		"src/sub/b.txt":   "b",
+		"src/sub/c/d.txt": "d",
+	})
+
+	src := filic.NewDirectory(path.Join(getTempDirPath(), "src"))
+	dest := filic.NewDirectory(path.Join(getTempDirPath(), "dest"))
+
+	err := src.CopyToParallel(context.Background(), dest, filic.ParallelOptions{Concurrency: 2})
+	if err != nil {
+		t.Error(err)
+	}
+
+	for name, expected := range map[string]string{"a.txt": "a", "sub/b.txt": "b", "sub/c/d.txt": "d"} {
+		content, err := filic.NewFile(dest.Join(name)).ReadString()
+		if err != nil {
+			t.Error(err)
+		}
+		if content != expected {
+			t.Errorf("Expected %q in %v, got %q", expected, name, content)
+		}
+	}
+
+	cleanup()
+}
+
+func TestHashAll(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{"a.txt": "hello world"})
+
+	files := []*filic.File{
+		filic.NewFile(dir.Join("a.txt")),
+		filic.NewFile(dir.Join("missing.txt")),
+	}
+
+	digests, err := filic.HashAll(context.Background(), files, filic.ParallelOptions{})
+	if !errors.Is(err, filic.ErrNotExist) {
+		t.Errorf("Expected ErrNotExist, got %v", err)
+	}
+
+	if digests[0] != "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9" {
+		t.Errorf("Unexpected digest %v", digests[0])
+	}
+	if digests[1] != "" {
+		t.Errorf("Expected empty digest for missing file, got %v", digests[1])
+	}
+
+	cleanup()
+}
+
+func TestChmodAndDeleteAll(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{"a.txt": "a", "sub/b.txt": "b"})
+
+	entities := []*filic.Entity{
+		filic.NewEntity(dir.Join("a.txt")),
+		filic.NewEntity(dir.Join("sub/b.txt")),
+	}
+
+	err := filic.ChmodAll(context.Background(), entities, 0600, filic.ParallelOptions{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	for _, entity := range entities {
+		info, err := os.Stat(entity.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("Expected mode 0600 for %v, got %v", entity.Path, info.Mode().Perm())
+		}
+	}
+
+	err = filic.DeleteAll(context.Background(), []*filic.Entity{
+		filic.NewEntity(dir.Join("a.txt")),
+		filic.NewEntity(dir.Join("sub")),
+	}, filic.ParallelOptions{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	names, err := dir.List()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(names) != 0 {
+		t.Errorf("Expected empty directory, got %v", names)
+	}
+
+	cleanup()
+}
@@ -0,0 +1,312 @@
+package filic
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// queryKind restricts a Query to files, directories or both.
+type queryKind int
+
+const (
+	queryAny queryKind = iota
+	queryFiles
+	queryDirs
+)
+
+// Query finds entities below a directory matching a set of predicates. It is
+// created with Directory.Find and configured by chaining its methods:
+//
+//	logs, err := dir.Find().Files().Ext(".log").LargerThan(10 << 20).CollectFiles()
+//
+// Predicates on the name are checked before anything else, so entries that
+// can be rejected by name are never statted. Size, time, mode and owner
+// predicates read the entity's FileInfo, which is cached in the result.
+// Symbolic links are followed; a link whose target cannot be reached fails
+// the type and FileInfo predicates instead of the query.
+type Query struct {
+	dir       *Directory
+	kind      queryKind
+	maxDepth  int
+	hidden    bool
+	nameTests []func(name string) bool
+	infoTests []func(info fs.FileInfo) bool
+	tests     []func(entity *Entity) bool
+	ignore    *IgnoreMatcher
+	err       error
+}
+
+// Find returns a Query matching every entity below the directory, at any
+// depth, including hidden ones. Use the Query methods to narrow it down.
+func (d *Directory) Find() *Query {
+	return &Query{dir: d, hidden: true}
+}
+
+// Files restricts the query to files.
+func (q *Query) Files() *Query {
+	q.kind = queryFiles
+	return q
+}
+
+// Dirs restricts the query to directories.
+func (q *Query) Dirs() *Query {
+	q.kind = queryDirs
+	return q
+}
+
+// Depth limits how deep the query descends. A depth of 1 only considers the
+// direct children of the directory. Zero, the default, means no limit.
+func (q *Query) Depth(depth int) *Query {
+	q.maxDepth = depth
+	return q
+}
+
+// Hidden controls whether hidden entities, whose name starts with a dot, are
+// considered. When false, hidden directories are not descended into either.
+func (q *Query) Hidden(hidden bool) *Query {
+	q.hidden = hidden
+	return q
+}
+
+// Ignore excludes the entities matched by m from the query. Ignored
+// directories are not descended into.
+func (q *Query) Ignore(m *IgnoreMatcher) *Query {
+	q.ignore = m
+	return q
+}
+
+// Name matches entities whose name matches the shell pattern, as
+// understood by path.Match.
+func (q *Query) Name(pattern string) *Query {
+	if _, err := path.Match(pattern, ""); err != nil {
+		q.err = err
+	}
+	q.nameTests = append(q.nameTests, func(name string) bool {
+		ok, _ := path.Match(pattern, name)
+		return ok
+	})
+	return q
+}
+
+// NameRegex matches entities whose name matches the regular expression.
+// An invalid expression makes the query fail when it is run.
+func (q *Query) NameRegex(expr string) *Query {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		q.err = err
+		return q
+	}
+	q.nameTests = append(q.nameTests, re.MatchString)
+	return q
+}
+
+// Ext matches entities whose name ends with one of the given extensions,
+// including the dot, such as ".log".
+func (q *Query) Ext(exts ...string) *Query {
+	q.nameTests = append(q.nameTests, func(name string) bool {
+		ext := path.Ext(name)
+		for _, e := range exts {
+			if ext == e {
+				return true
+			}
+		}
+		return false
+	})
+	return q
+}
+
+// LargerThan matches entities bigger than size bytes.
+func (q *Query) LargerThan(size int64) *Query {
+	return q.whereInfo(func(info fs.FileInfo) bool {
+		return info.Size() > size
+	})
+}
+
+// SmallerThan matches entities smaller than size bytes.
+func (q *Query) SmallerThan(size int64) *Query {
+	return q.whereInfo(func(info fs.FileInfo) bool {
+		return info.Size() < size
+	})
+}
+
+// ModifiedBefore matches entities last modified before t.
+func (q *Query) ModifiedBefore(t time.Time) *Query {
+	return q.whereInfo(func(info fs.FileInfo) bool {
+		return info.ModTime().Before(t)
+	})
+}
+
+// ModifiedAfter matches entities last modified after t.
+func (q *Query) ModifiedAfter(t time.Time) *Query {
+	return q.whereInfo(func(info fs.FileInfo) bool {
+		return info.ModTime().After(t)
+	})
+}
+
+// Perm matches entities having all of the permission bits in mask set.
+func (q *Query) Perm(mask fs.FileMode) *Query {
+	return q.whereInfo(func(info fs.FileInfo) bool {
+		return info.Mode().Perm()&mask == mask
+	})
+}
+
+// Owner matches entities owned by the user with the given ID. On platforms
+// without file ownership nothing matches.
+func (q *Query) Owner(uid int) *Query {
+	return q.whereInfo(func(info fs.FileInfo) bool {
+		owner, _, ok := fileOwner(info)
+		return ok && owner == uid
+	})
+}
+
+// Where matches entities for which fn returns true. It is checked after all
+// other predicates.
+func (q *Query) Where(fn func(entity *Entity) bool) *Query {
+	q.tests = append(q.tests, fn)
+	return q
+}
+
+func (q *Query) whereInfo(fn func(info fs.FileInfo) bool) *Query {
+	q.infoTests = append(q.infoTests, fn)
+	return q
+}
+
+// Collect runs the query and returns the matching entities in walk order.
+func (q *Query) Collect() ([]*Entity, error) {
+	return q.CollectContext(context.Background())
+}
+
+// CollectContext is like Collect but stops and returns the context's error
+// once ctx is cancelled.
+func (q *Query) CollectContext(ctx context.Context) ([]*Entity, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	var matches []*Entity
+	err := q.dir.WalkWithOptions(ctx, WalkOptions{Ignore: q.ignore}, func(entity *Entity) error {
+		match, err := q.match(entity)
+		if err != nil {
+			return err
+		}
+		if match {
+			matches = append(matches, entity)
+		}
+
+		if !q.descend(entity) {
+			return SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return matches, nil
+}
+
+// CollectFiles runs the query and returns the matching files. Directories
+// matched by the query are left out.
+func (q *Query) CollectFiles() ([]*File, error) {
+	entities, err := q.Files().Collect()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]*File, len(entities))
+	for i, entity := range entities {
+		files[i] = &File{Entity: *entity}
+	}
+	return files, nil
+}
+
+// CollectDirs runs the query and returns the matching directories. Files
+// matched by the query are left out.
+func (q *Query) CollectDirs() ([]*Directory, error) {
+	entities, err := q.Dirs().Collect()
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make([]*Directory, len(entities))
+	for i, entity := range entities {
+		dirs[i] = &Directory{Entity: *entity}
+	}
+	return dirs, nil
+}
+
+// depth returns how many levels below the query's directory entity is.
+func (q *Query) depth(entity *Entity) int {
+	return strings.Count(relativePath(q.dir.Path, entity.Path), "/") + 1
+}
+
+// descend reports whether the walk should continue into entity.
+func (q *Query) descend(entity *Entity) bool {
+	if !q.hidden && isHidden(entity.Name()) {
+		return false
+	}
+	return q.maxDepth <= 0 || q.depth(entity) < q.maxDepth
+}
+
+// match checks the predicates from cheapest to most expensive: the name,
+// the type bits from the listing and finally the FileInfo.
+func (q *Query) match(entity *Entity) (bool, error) {
+	name := entity.Name()
+
+	if !q.hidden && isHidden(name) {
+		return false, nil
+	}
+	for _, test := range q.nameTests {
+		if !test(name) {
+			return false, nil
+		}
+	}
+
+	if q.kind != queryAny {
+		isDir, err := entity.IsDirectory()
+		if err != nil {
+			return false, brokenLinkError(entity, err)
+		}
+		if isDir != (q.kind == queryDirs) {
+			return false, nil
+		}
+	}
+
+	if len(q.infoTests) > 0 {
+		info, err := entity.Info()
+		if err != nil {
+			return false, brokenLinkError(entity, err)
+		}
+		for _, test := range q.infoTests {
+			if !test(info) {
+				return false, nil
+			}
+		}
+	}
+
+	for _, test := range q.tests {
+		if !test(entity) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// brokenLinkError returns nil if err comes from following entity, a
+// symbolic link whose target is missing or unreachable, and err otherwise.
+func brokenLinkError(entity *Entity, err error) error {
+	if info, lerr := entity.lstat(); lerr == nil && info.Mode()&fs.ModeSymlink != 0 {
+		return nil
+	}
+	return err
+}
+
+// isHidden reports whether name is hidden by the Unix dot-file convention.
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
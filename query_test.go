@@ -0,0 +1,147 @@
+package filic_test
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henilmalaviya/filic"
+)
+
+// relNames returns the paths of entities relative to dir, sorted.
+func relNames(dir *filic.Directory, entities []*filic.Entity) []string {
+	var names []string
+	for _, entity := range entities {
+		names = append(names, strings.TrimPrefix(entity.Path, dir.Path+"/"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func expectNames(t *testing.T, expected, got []string) {
+	t.Helper()
+
+	if strings.Join(expected, ",") != strings.Join(got, ",") {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestFindFilesByExtAndSize(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		"small.log":         "x",
+		"big.log":           strings.Repeat("x", 100),
+		"big.txt":           strings.Repeat("x", 100),
+		"nested/deep.log":   strings.Repeat("x", 100),
+		".hidden/big.log":   strings.Repeat("x", 100),
+		"nested/.big.log":   strings.Repeat("x", 100),
+		"a/b/c/toodeep.log": strings.Repeat("x", 100),
+	})
+
+	files, err := dir.Find().Ext(".log").LargerThan(10).Depth(2).Hidden(false).CollectFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entities []*filic.Entity
+	for _, file := range files {
+		entities = append(entities, &file.Entity)
+	}
+
+	expectNames(t, []string{"big.log", "nested/deep.log"}, relNames(dir, entities))
+
+	cleanup()
+}
+
+func TestFindDirsAndName(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		"src/main.go":      "",
+		"src/util/util.go": "",
+		"docs/readme.md":   "",
+	})
+
+	dirs, err := dir.Find().Dirs().Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNames(t, []string{"docs", "src", "src/util"}, relNames(dir, dirs))
+
+	goFiles, err := dir.Find().Name("*.go").Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNames(t, []string{"src/main.go", "src/util/util.go"}, relNames(dir, goFiles))
+
+	matched, err := dir.Find().NameRegex(`^u.*\.go$`).Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNames(t, []string{"src/util/util.go"}, relNames(dir, matched))
+
+	_, err = dir.Find().NameRegex(`(`).Collect()
+	if err == nil {
+		t.Error("Expected error for invalid regex")
+	}
+
+	cleanup()
+}
+
+func TestFindModifiedAndPerm(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{"old.txt": "", "new.txt": ""})
+
+	old := time.Now().Add(-48 * time.Hour)
+	os.Chtimes(dir.Join("old.txt"), old, old)
+	os.Chmod(dir.Join("new.txt"), 0755)
+
+	matched, err := dir.Find().ModifiedBefore(time.Now().Add(-24 * time.Hour)).Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNames(t, []string{"old.txt"}, relNames(dir, matched))
+
+	matched, err = dir.Find().Perm(0100).Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNames(t, []string{"new.txt"}, relNames(dir, matched))
+
+	matched, err = dir.Find().Owner(os.Getuid()).Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNames(t, []string{"new.txt", "old.txt"}, relNames(dir, matched))
+
+	cleanup()
+}
+
+func TestFindSkipsBrokenLinks(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		"a.txt":     "a",
+		"sub/b.txt": "bb",
+	})
+	os.Symlink("missing.txt", dir.Join("dangling"))
+	os.Symlink("a.txt", dir.Join("link"))
+
+	files, err := dir.Find().Files().Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNames(t, []string{"a.txt", "link", "sub/b.txt"}, relNames(dir, files))
+
+	large, err := dir.Find().Files().LargerThan(1).Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNames(t, []string{"sub/b.txt"}, relNames(dir, large))
+
+	cleanup()
+}
@@ -0,0 +1,363 @@
+package filic
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"iter"
+	"os"
+	"sync"
+	"time"
+)
+
+// recordHeaderLen is the size of the header preceding every record: the
+// payload length, a CRC-32C of the length and a CRC-32C of the payload, all
+// little endian. The checksum of the length lets a header be recognized
+// without reading the payload.
+const recordHeaderLen = 12
+
+// maxRecordLen bounds the size of a single record.
+const maxRecordLen = 1 << 30
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// SyncPolicy selects when a RecordLog flushes appended records to stable
+// storage.
+type SyncPolicy int
+
+const (
+	// SyncAlways syncs after every append, so an append that returned is
+	// never lost.
+	SyncAlways SyncPolicy = iota
+	// SyncInterval syncs in the background at a fixed interval, bounding
+	// how much can be lost.
+	SyncInterval
+	// SyncNever leaves flushing to the operating system.
+	SyncNever
+)
+
+// RecordLogOptions configures OpenRecordLog.
+type RecordLogOptions struct {
+	// Sync selects when appended records are synced.
+	Sync SyncPolicy
+	// SyncInterval is the interval used by SyncInterval. Zero means one
+	// second.
+	SyncInterval time.Duration
+}
+
+// RecordLog is an append-only log of records stored in a File. Every record
+// is length-prefixed and checksummed, so a record torn by a crash or power
+// loss is detected and dropped when the log is opened again. A RecordLog is
+// safe for concurrent use.
+type RecordLog struct {
+	mu     sync.Mutex
+	file   *os.File
+	path   string
+	policy SyncPolicy
+	size   int64
+	dirty  bool
+	closed bool
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// OpenRecordLog opens the record log stored in f, creating the file if it
+// doesn't exist. A record at the end of the file that is incomplete or
+// fails its checksum is truncated away; damage before the last record
+// returns an error matching ErrCorruptRecord and leaves the file alone.
+func OpenRecordLog(f *File, opts RecordLogOptions) (*RecordLog, error) {
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, newPathError("openlog", f.Path, err)
+	}
+
+	size, err := recoverRecords(file)
+	if err != nil {
+		file.Close()
+		return nil, newPathError("openlog", f.Path, err)
+	}
+
+	l := &RecordLog{file: file, path: f.Path, policy: opts.Sync, size: size}
+
+	if opts.Sync == SyncInterval {
+		interval := opts.SyncInterval
+		if interval <= 0 {
+			interval = time.Second
+		}
+		l.stop, l.done = make(chan struct{}), make(chan struct{})
+		go l.syncEvery(interval)
+	}
+
+	return l, nil
+}
+
+// recoverRecords finds the end of the last valid record of the log, cuts
+// off anything after it and positions the file there for appending. Only a
+// damaged tail, as left by an interrupted append, is cut off: a damaged
+// record followed by more data returns an error matching ErrCorruptRecord.
+func recoverRecords(file *os.File) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+
+	var valid int64
+	r := bufio.NewReader(file)
+	for {
+		_, n, err := readRecord(r, size-valid)
+		if err != nil {
+			break
+		}
+		valid += n
+	}
+
+	if size != valid {
+		tail, err := isTornTail(file, valid, size)
+		if err != nil {
+			return 0, err
+		}
+		if !tail {
+			return 0, fmt.Errorf("%w at offset %d", ErrCorruptRecord, valid)
+		}
+
+		if err := file.Truncate(valid); err != nil {
+			return 0, err
+		}
+		if err := file.Sync(); err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = file.Seek(valid, io.SeekStart)
+	return valid, err
+}
+
+// isTornTail reports whether the invalid data from offset to the end of
+// the file can be the result of an interrupted append, which leaves no
+// valid record after the one it tore. A damaged length can make a record
+// in the middle of the log look like it runs past the end, so the rest of
+// the file is searched for a valid record rather than trusting it. Only
+// positions holding a valid header have their payload read.
+func isTornTail(file *os.File, offset, size int64) (bool, error) {
+	buf := make([]byte, 64*1024+recordHeaderLen)
+	payload := make([]byte, 64*1024)
+	crc := crc32.New(crc32c)
+
+	for pos := offset + 1; pos+recordHeaderLen <= size; {
+		n, err := file.ReadAt(buf, pos)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return false, err
+		}
+		if n < recordHeaderLen {
+			break
+		}
+
+		for i := 0; i+recordHeaderLen <= n; i++ {
+			start := pos + int64(i)
+			length, ok := parseRecordHeader(buf[i : i+recordHeaderLen])
+			if !ok || start+recordHeaderLen+length > size {
+				continue
+			}
+
+			crc.Reset()
+			r := io.NewSectionReader(file, start+recordHeaderLen, length)
+			if _, err := io.CopyBuffer(crc, r, payload); err != nil {
+				return false, err
+			}
+			if crc.Sum32() == binary.LittleEndian.Uint32(buf[i+8:i+12]) {
+				return false, nil
+			}
+		}
+		pos += int64(n - recordHeaderLen + 1)
+	}
+	return true, nil
+}
+
+// errTornRecord is returned by readRecord for a record that is incomplete or
+// fails its checksum.
+var errTornRecord = errors.New("torn record")
+
+// readRecord reads one record from r, which has remaining bytes left,
+// returning its payload and its size including the header. It returns
+// io.EOF at a clean end of the log.
+func readRecord(r io.Reader, remaining int64) ([]byte, int64, error) {
+	var header [recordHeaderLen]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, 0, io.EOF
+		}
+		return nil, 0, errTornRecord
+	}
+
+	// checked before allocating, as a damaged length can be anything
+	length, ok := parseRecordHeader(header[:])
+	if !ok || recordHeaderLen+length > remaining {
+		return nil, 0, errTornRecord
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, 0, errTornRecord
+	}
+
+	if crc32.Checksum(payload, crc32c) != binary.LittleEndian.Uint32(header[8:12]) {
+		return nil, 0, errTornRecord
+	}
+
+	return payload, recordHeaderLen + length, nil
+}
+
+// parseRecordHeader returns the payload length stored in a record header,
+// and whether the header is intact.
+func parseRecordHeader(header []byte) (int64, bool) {
+	if crc32.Checksum(header[0:4], crc32c) != binary.LittleEndian.Uint32(header[4:8]) {
+		return 0, false
+	}
+	length := int64(binary.LittleEndian.Uint32(header[0:4]))
+	return length, length <= maxRecordLen
+}
+
+// Append adds a record to the end of the log. With SyncAlways the record is
+// on stable storage when Append returns.
+func (l *RecordLog) Append(data []byte) error {
+	if len(data) > maxRecordLen {
+		return &PathError{Op: "append", Path: l.path, Err: ErrRecordTooLarge}
+	}
+
+	record := make([]byte, recordHeaderLen, recordHeaderLen+len(data))
+	binary.LittleEndian.PutUint32(record[0:4], uint32(len(data)))
+	binary.LittleEndian.PutUint32(record[4:8], crc32.Checksum(record[0:4], crc32c))
+	binary.LittleEndian.PutUint32(record[8:12], crc32.Checksum(data, crc32c))
+	record = append(record, data...)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return &PathError{Op: "append", Path: l.path, Err: os.ErrClosed}
+	}
+
+	if _, err := l.file.Write(record); err != nil {
+		// drop whatever part of the record made it to the file
+		l.file.Truncate(l.size)
+		l.file.Seek(l.size, io.SeekStart)
+		return newPathError("append", l.path, err)
+	}
+	l.size += int64(len(record))
+	l.dirty = true
+
+	if l.policy == SyncAlways {
+		return l.syncLocked()
+	}
+	return nil
+}
+
+// Sync flushes appended records to stable storage.
+func (l *RecordLog) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return &PathError{Op: "sync", Path: l.path, Err: os.ErrClosed}
+	}
+	return l.syncLocked()
+}
+
+func (l *RecordLog) syncLocked() error {
+	if !l.dirty {
+		return nil
+	}
+	if err := l.file.Sync(); err != nil {
+		return newPathError("sync", l.path, err)
+	}
+	l.dirty = false
+	return nil
+}
+
+// syncEvery syncs the log at every interval until it is closed.
+func (l *RecordLog) syncEvery(interval time.Duration) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			l.Sync()
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// Records iterates over the records of the log, from the oldest, as they
+// are at the time iteration starts. Records appended meanwhile are not
+// included.
+func (l *RecordLog) Records() iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		l.mu.Lock()
+		size := l.size
+		l.mu.Unlock()
+
+		file, err := os.Open(l.path)
+		if err != nil {
+			yield(nil, newPathError("read", l.path, err))
+			return
+		}
+		defer file.Close()
+
+		r := bufio.NewReader(io.LimitReader(file, size))
+		for offset := int64(0); ; {
+			payload, n, err := readRecord(r, size-offset)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(nil, newPathError("read", l.path, err))
+				return
+			}
+			if !yield(payload, nil) {
+				return
+			}
+			offset += n
+		}
+	}
+}
+
+// Size returns the size of the log in bytes, including record headers.
+func (l *RecordLog) Size() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.size
+}
+
+// Close syncs the log, unless its policy is SyncNever, and closes it.
+func (l *RecordLog) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+
+	var err error
+	if l.policy != SyncNever {
+		err = l.syncLocked()
+	}
+	l.closed = true
+	l.mu.Unlock()
+
+	if l.stop != nil {
+		close(l.stop)
+		<-l.done
+	}
+
+	if closeErr := l.file.Close(); err == nil {
+		err = newPathError("close", l.path, closeErr)
+	}
+	return err
+}
@@ -0,0 +1,258 @@
+package filic_test
+
+import (
+	"errors"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func readRecords(t *testing.T, log *filic.RecordLog) []string {
+	t.Helper()
+
+	var records []string
+	for record, err := range log.Records() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, string(record))
+	}
+	return records
+}
+
+func TestRecordLogAppendAndReplay(t *testing.T) {
+	cleanup()
+
+	dir := filic.NewDirectory(getTempDirPath())
+	dir.Create()
+	file := filic.NewFile(dir.Join("events.log"))
+
+	log, err := filic.OpenRecordLog(file, filic.RecordLogOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 3 {
+		if err := log.Append([]byte("event " + strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	log.Append(nil)
+
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err = filic.OpenRecordLog(file, filic.RecordLogOptions{Sync: filic.SyncNever})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	records := readRecords(t, log)
+	expected := []string{"event 0", "event 1", "event 2", ""}
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %v records, got %v", len(expected), records)
+	}
+	for i := range expected {
+		if records[i] != expected[i] {
+			t.Errorf("Expected record %v to be %q, got %q", i, expected[i], records[i])
+		}
+	}
+
+	cleanup()
+}
+
+func TestRecordLogTruncatesTornTail(t *testing.T) {
+	cleanup()
+
+	dir := filic.NewDirectory(getTempDirPath())
+	dir.Create()
+	file := filic.NewFile(dir.Join("events.log"))
+
+	log, err := filic.OpenRecordLog(file, filic.RecordLogOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Append([]byte("first"))
+	log.Append([]byte("second"))
+	size := log.Size()
+	log.Close()
+
+	// simulate a record cut short by a crash
+	if err := file.Append([]byte{20, 0, 0, 0, 1, 2, 3, 4, 'p', 'a', 'r'}); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err = filic.OpenRecordLog(file, filic.RecordLogOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if log.Size() != size {
+		t.Errorf("Expected size %v after recovery, got %v", size, log.Size())
+	}
+
+	info, _ := os.Stat(file.Path)
+	if info.Size() != size {
+		t.Errorf("Expected file to be truncated to %v, got %v", size, info.Size())
+	}
+
+	log.Append([]byte("third"))
+	records := readRecords(t, log)
+	if len(records) != 3 || records[2] != "third" {
+		t.Errorf("Expected appends after recovery to follow valid records, got %v", records)
+	}
+	log.Close()
+
+	cleanup()
+}
+
+func TestRecordLogTruncatesLargeTornRecord(t *testing.T) {
+	cleanup()
+
+	dir := filic.NewDirectory(getTempDirPath())
+	dir.Create()
+	file := filic.NewFile(dir.Join("events.log"))
+
+	payload := make([]byte, 16<<20)
+	rand.NewChaCha8([32]byte{}).Read(payload)
+
+	log, _ := filic.OpenRecordLog(file, filic.RecordLogOptions{Sync: filic.SyncNever})
+	log.Append([]byte("first"))
+	size := log.Size()
+	log.Append(payload)
+	log.Close()
+
+	// the large record is torn halfway through its payload
+	if err := os.Truncate(file.Path, size+8<<20); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	log, err := filic.OpenRecordLog(file, filic.RecordLogOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected recovery to scan the torn record quickly, took %v", elapsed)
+	}
+	if records := readRecords(t, log); len(records) != 1 || log.Size() != size {
+		t.Errorf("Expected the torn record to be truncated, got %d records and %d bytes", len(records), log.Size())
+	}
+
+	cleanup()
+}
+
+func TestRecordLogChecksumCorruption(t *testing.T) {
+	cleanup()
+
+	dir := filic.NewDirectory(getTempDirPath())
+	dir.Create()
+	file := filic.NewFile(dir.Join("events.log"))
+
+	log, _ := filic.OpenRecordLog(file, filic.RecordLogOptions{})
+	log.Append([]byte("good"))
+	log.Append([]byte("flipped"))
+	log.Close()
+
+	data, _ := file.Read()
+	data[len(data)-1] ^= 0xff
+	file.Write(data)
+
+	log, err := filic.OpenRecordLog(file, filic.RecordLogOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	records := readRecords(t, log)
+	if len(records) != 1 || records[0] != "good" {
+		t.Errorf("Expected only the valid record to survive, got %v", records)
+	}
+
+	cleanup()
+}
+
+func TestRecordLogCorruptionBeforeTail(t *testing.T) {
+	cleanup()
+
+	dir := filic.NewDirectory(getTempDirPath())
+	dir.Create()
+	file := filic.NewFile(dir.Join("events.log"))
+
+	log, _ := filic.OpenRecordLog(file, filic.RecordLogOptions{})
+	log.Append([]byte("first"))
+	log.Append([]byte("second"))
+	log.Close()
+
+	// a damaged payload and a damaged length in the first record
+	for _, offset := range []int{12, 0} {
+		data, _ := file.Read()
+		data[offset] ^= 0xff
+		file.Write(data)
+
+		_, err := filic.OpenRecordLog(file, filic.RecordLogOptions{})
+		if !errors.Is(err, filic.ErrCorruptRecord) {
+			t.Errorf("Expected ErrCorruptRecord, got %v", err)
+		}
+
+		after, _ := file.Read()
+		if len(after) != len(data) {
+			t.Errorf("Expected the log to be left alone, got %d bytes instead of %d", len(after), len(data))
+		}
+		data[offset] ^= 0xff
+		file.Write(data)
+	}
+
+	// zeros after the last record are a torn tail
+	data, _ := file.Read()
+	file.Write(append(data, make([]byte, 64)...))
+
+	log, err := filic.OpenRecordLog(file, filic.RecordLogOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	if records := readRecords(t, log); len(records) != 2 || log.Size() != int64(len(data)) {
+		t.Errorf("Expected the zeros to be truncated, got %v and %d bytes", records, log.Size())
+	}
+
+	cleanup()
+}
+
+func TestRecordLogSyncIntervalAndClosed(t *testing.T) {
+	cleanup()
+
+	dir := filic.NewDirectory(getTempDirPath())
+	dir.Create()
+
+	log, err := filic.OpenRecordLog(filic.NewFile(dir.Join("events.log")), filic.RecordLogOptions{
+		Sync:         filic.SyncInterval,
+		SyncInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := log.Append([]byte("x")); err != nil {
+		t.Error(err)
+	}
+
+	if err := log.Close(); err != nil {
+		t.Error(err)
+	}
+
+	if err := log.Append([]byte("y")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Expected os.ErrClosed after Close, got %v", err)
+	}
+
+	cleanup()
+}
@@ -0,0 +1,7 @@
+//go:build linux && !(mips || mipsle || mips64 || mips64le || ppc || ppc64 || ppc64le || sparc64)
+
+package filic
+
+// ficlone is the FICLONE ioctl, sharing the extents of one file with
+// another on file systems supporting copy-on-write.
+const ficlone = 0x40049409
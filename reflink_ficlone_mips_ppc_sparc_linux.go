@@ -0,0 +1,8 @@
+//go:build linux && (mips || mipsle || mips64 || mips64le || ppc || ppc64 || ppc64le || sparc64)
+
+package filic
+
+// ficlone is the FICLONE ioctl, sharing the extents of one file with
+// another on file systems supporting copy-on-write. These architectures
+// encode the write direction of an ioctl in a different bit.
+const ficlone = 0x80049409
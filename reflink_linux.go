@@ -0,0 +1,33 @@
+//go:build linux
+
+package filic
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// reflinkFile creates dst as a copy-on-write clone of src, with the mode
+// and modification time described by info.
+func reflinkFile(src, dst string, info fs.FileInfo) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, out.Fd(), ficlone, in.Fd())
+	if closeErr := out.Close(); errno == 0 && closeErr != nil {
+		return closeErr
+	}
+	if errno != 0 {
+		return errno
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
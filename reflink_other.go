@@ -0,0 +1,13 @@
+//go:build !linux
+
+package filic
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// reflinkFile reports that reflinks are not supported on this platform.
+func reflinkFile(src, dst string, info fs.FileInfo) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,211 @@
+package filic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// replacer performs either a literal or a regular expression replacement.
+type replacer struct {
+	old  string
+	new  string
+	re   *regexp.Regexp
+	repl string
+}
+
+// apply returns content with the replacements made and how many there were.
+func (r replacer) apply(content string) (string, int) {
+	if r.re != nil {
+		count := len(r.re.FindAllStringIndex(content, -1))
+		if count == 0 {
+			return content, 0
+		}
+		return r.re.ReplaceAllString(content, r.repl), count
+	}
+
+	count := strings.Count(content, r.old)
+	if count == 0 {
+		return content, 0
+	}
+	return strings.ReplaceAll(content, r.old, r.new), count
+}
+
+// replaceFile applies r to the file at p, atomically rewriting it when
+// anything was replaced. It returns the old and new content and the number
+// of replacements made. With dryRun the file is not written.
+func replaceFile(p string, r replacer, dryRun bool) (string, string, int, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return "", "", 0, newPathError("replace", p, err)
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return "", "", 0, newPathError("replace", p, err)
+	}
+
+	content := string(data)
+	replaced, count := r.apply(content)
+	if count == 0 || replaced == content || dryRun {
+		return content, replaced, count, nil
+	}
+
+	err = atomicWriteFile(p, info.Mode().Perm(), func(w io.Writer) error {
+		_, err := io.WriteString(w, replaced)
+		return err
+	})
+	return content, replaced, count, err
+}
+
+// Replace replaces every occurrence of old in the file with new and returns
+// the number of replacements. The file is rewritten atomically, and only if
+// something was replaced.
+func (f *File) Replace(old, new string) (int, error) {
+	if old == "" {
+		return 0, &PathError{Op: "replace", Path: f.Path, Err: errors.New("empty search string")}
+	}
+	_, _, count, err := replaceFile(f.Path, replacer{old: old, new: new}, false)
+	return count, err
+}
+
+// ReplaceRegex replaces every match of re in the file with repl, which may
+// refer to submatches like regexp.Regexp.ReplaceAllString, and returns the
+// number of matches. The file is rewritten atomically, and only if
+// something was replaced.
+func (f *File) ReplaceRegex(re *regexp.Regexp, repl string) (int, error) {
+	_, _, count, err := replaceFile(f.Path, replacer{re: re, repl: repl}, false)
+	return count, err
+}
+
+// ReplaceOptions configures Directory.Replace.
+type ReplaceOptions struct {
+	// Old is the literal text to replace with New. It is ignored when Regex
+	// is set.
+	Old, New string
+	// Regex, if set, is replaced with Replacement instead of Old with New.
+	Regex       *regexp.Regexp
+	Replacement string
+	// Glob, if set, restricts the replacement to files whose name matches
+	// it, or whose relative path does when it contains a "/". The syntax is
+	// the one of path.Match.
+	Glob string
+	// Ignore, if set, excludes matching files and directories.
+	Ignore *IgnoreMatcher
+	// DryRun computes the replacements and their diffs without writing.
+	DryRun bool
+	// DiffContext is the number of context lines in the diffs of the
+	// report. Zero uses 3.
+	DiffContext int
+}
+
+// FileReplacement describes the replacements made in one file.
+type FileReplacement struct {
+	// Path is the path of the file relative to the directory.
+	Path string `json:"path"`
+	// Count is the number of replacements made in the file.
+	Count int `json:"count"`
+	// Diff is the change as a unified diff.
+	Diff string `json:"diff"`
+}
+
+// ReplaceReport summarizes a Directory.Replace.
+type ReplaceReport struct {
+	// Files lists the files with replacements, in walk order.
+	Files []FileReplacement `json:"files"`
+	// Total is the number of replacements across all files.
+	Total int `json:"total"`
+}
+
+// Diff returns the unified diffs of all files in the report.
+func (r *ReplaceReport) Diff() string {
+	var out strings.Builder
+	for _, file := range r.Files {
+		out.WriteString(file.Diff)
+	}
+	return out.String()
+}
+
+// Replace performs a search-and-replace across all text files below the
+// directory, atomically rewriting each changed file. Binary files are
+// skipped. The report lists the replacements made per file with a unified
+// diff, which makes a dry run a preview of the change.
+func (d *Directory) Replace(ctx context.Context, opts ReplaceOptions) (*ReplaceReport, error) {
+	r := replacer{old: opts.Old, new: opts.New, re: opts.Regex, repl: opts.Replacement}
+	if r.re == nil && r.old == "" {
+		return nil, &PathError{Op: "replace", Path: d.Path, Err: errors.New("empty search string")}
+	}
+	if opts.Glob != "" {
+		if _, err := path.Match(opts.Glob, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	contextLines := opts.DiffContext
+	if contextLines <= 0 {
+		contextLines = 3
+	}
+
+	report := &ReplaceReport{}
+	err := d.WalkWithOptions(ctx, WalkOptions{Ignore: opts.Ignore}, func(entity *Entity) error {
+		if !entity.entry.Type().IsRegular() {
+			return nil
+		}
+
+		rel := relativePath(d.Path, entity.Path)
+		if opts.Glob != "" {
+			target := entity.Name()
+			if strings.Contains(opts.Glob, "/") {
+				target = rel
+			}
+			if ok, _ := path.Match(opts.Glob, target); !ok {
+				return nil
+			}
+		}
+
+		if binary, err := fileIsBinary(entity.Path); err != nil || binary {
+			return err
+		}
+
+		old, replaced, count, err := replaceFile(entity.Path, r, opts.DryRun)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+
+		report.Files = append(report.Files, FileReplacement{
+			Path:  rel,
+			Count: count,
+			Diff:  UnifiedDiff("a/"+rel, "b/"+rel, old, replaced, contextLines),
+		})
+		report.Total += count
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// fileIsBinary reports whether the file at p looks binary.
+func fileIsBinary(p string) (bool, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return false, newPathError("read", p, err)
+	}
+	defer file.Close()
+
+	var head bytes.Buffer
+	if _, err := io.CopyN(&head, file, binarySniffLen); err != nil && !errors.Is(err, io.EOF) {
+		return false, newPathError("read", p, err)
+	}
+	return isBinary(head.Bytes()), nil
+}
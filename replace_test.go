@@ -0,0 +1,103 @@
+package filic_test
+
+import (
+	"context"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func TestFileReplace(t *testing.T) {
+	cleanup()
+
+	file := filic.NewFile(path.Join(getTempDirPath(), "config.txt"))
+	file.Create()
+	file.Write([]byte("host=old\nbackup=old\n"))
+	os.Chmod(file.Path, 0600)
+
+	count, err := file.Replace("old", "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 replacements, got %d", count)
+	}
+
+	content, _ := file.ReadString()
+	if content != "host=new\nbackup=new\n" {
+		t.Errorf("Unexpected content %q", content)
+	}
+
+	info, _ := os.Stat(file.Path)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode to be kept, got %v", info.Mode().Perm())
+	}
+
+	count, err = file.ReplaceRegex(regexp.MustCompile(`(\w+)=new`), "$1=${1}_value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 replacements, got %d", count)
+	}
+
+	content, _ = file.ReadString()
+	if content != "host=host_value\nbackup=backup_value\n" {
+		t.Errorf("Unexpected content %q", content)
+	}
+
+	cleanup()
+}
+
+func TestDirectoryReplace(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		"a.go":      "import \"old/pkg\"\n",
+		"sub/b.go":  "import \"old/pkg\"\nimport \"old/pkg\"\n",
+		"c.md":      "old/pkg in docs\n",
+		"binary.go": "old/pkg\x00",
+		"none.go":   "nothing here\n",
+	})
+
+	opts := filic.ReplaceOptions{Old: "old/pkg", New: "new/pkg", Glob: "*.go", DryRun: true}
+
+	report, err := dir.Replace(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Total != 3 || len(report.Files) != 2 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	if !strings.Contains(report.Diff(), "-import \"old/pkg\"\n+import \"new/pkg\"\n") {
+		t.Errorf("Unexpected diff:\n%s", report.Diff())
+	}
+
+	content, _ := filic.NewFile(dir.Join("a.go")).ReadString()
+	if !strings.Contains(content, "old/pkg") {
+		t.Error("Dry run should not change files")
+	}
+
+	opts.DryRun = false
+	if _, err := dir.Replace(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string]string{
+		"a.go":      "import \"new/pkg\"\n",
+		"c.md":      "old/pkg in docs\n",
+		"binary.go": "old/pkg\x00",
+	} {
+		content, _ := filic.NewFile(dir.Join(name)).ReadString()
+		if content != expected {
+			t.Errorf("Expected %q in %v, got %q", expected, name, content)
+		}
+	}
+
+	cleanup()
+}
@@ -0,0 +1,498 @@
+package filic
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotateTimeFormat is the timestamp used to name segments with
+// RotateTimestamp. It sorts lexically in chronological order.
+const rotateTimeFormat = "20060102T150405.000000000"
+
+// RotateNaming selects how a RotatingFile names rotated segments.
+type RotateNaming int
+
+const (
+	// RotateIndex names segments "app.log.1", "app.log.2" and so on, with
+	// 1 always being the most recent one.
+	RotateIndex RotateNaming = iota
+	// RotateTimestamp names segments after the time they were rotated, as
+	// in "app.log.20261018T150405.000000000".
+	RotateTimestamp
+)
+
+// RotatingFileOptions configures a RotatingFile. Zero values disable the
+// corresponding rotation trigger or retention limit.
+type RotatingFileOptions struct {
+	// MaxSize rotates the file before a write would make it larger than
+	// this many bytes. A single write larger than MaxSize still goes to a
+	// file of its own.
+	MaxSize int64
+	// Interval rotates the file when a write happens in a different
+	// interval than the file was last written in. Intervals are aligned
+	// to the zero time, so an Interval of 24 hours rotates at midnight UTC.
+	Interval time.Duration
+	// Naming selects how rotated segments are named.
+	Naming RotateNaming
+	// Compress gzips rotated segments, adding a ".gz" extension. Segments
+	// are compressed in the background, without holding up writes.
+	Compress bool
+	// MaxAge removes rotated segments last modified longer ago than this.
+	MaxAge time.Duration
+	// MaxCount keeps at most this many rotated segments.
+	MaxCount int
+}
+
+// RotatingFile is an io.Writer appending to a File that is rotated by size,
+// time or both. Rotated segments are kept next to the file and pruned
+// according to the retention options. A RotatingFile is safe for concurrent
+// use; each Write lands entirely in one segment.
+//
+// A write that triggers a rotation still goes through when only moving the
+// old file aside, compressing it or pruning segments fails; such errors are
+// returned by Close.
+type RotatingFile struct {
+	File *File
+
+	opts    RotatingFileOptions
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	started time.Time
+
+	// segMu serializes changes to the segments on disk, so that the
+	// compression of a segment doesn't hold up rotations
+	segMu sync.Mutex
+
+	// queue holds the segments waiting to be compressed, one at a time, by
+	// a background goroutine
+	queueMu     sync.Mutex
+	queueIdle   *sync.Cond
+	queue       []*os.File
+	compressing bool
+
+	errMu sync.Mutex
+	errs  []error
+}
+
+// NewRotatingFile opens f for appending, creating it if needed, and returns
+// a RotatingFile writing to it.
+func NewRotatingFile(f *File, opts RotatingFileOptions) (*RotatingFile, error) {
+	r := &RotatingFile{File: f, opts: opts}
+	r.queueIdle = sync.NewCond(&r.queueMu)
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens the current file, picking up the size and last modification
+// time of an existing one.
+func (r *RotatingFile) open() error {
+	file, err := os.OpenFile(r.File.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return newPathError("open", r.File.Path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return newPathError("open", r.File.Path, err)
+	}
+
+	r.file = file
+	r.size = info.Size()
+	r.started = info.ModTime()
+	return nil
+}
+
+// Write appends p to the file, rotating it first if p would exceed MaxSize
+// or the rotation interval has passed.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, &PathError{Op: "write", Path: r.File.Path, Err: os.ErrClosed}
+	}
+
+	if r.shouldRotate(int64(len(p))) {
+		if err := r.rotate(); err != nil {
+			if r.file == nil {
+				return 0, err
+			}
+			// the write goes to the reopened file all the same
+			r.deferError(err)
+		}
+	}
+
+	if r.size == 0 {
+		r.started = time.Now()
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	if err != nil {
+		return n, newPathError("write", r.File.Path, err)
+	}
+	return n, nil
+}
+
+func (r *RotatingFile) shouldRotate(n int64) bool {
+	if r.size == 0 {
+		return false
+	}
+	if r.opts.MaxSize > 0 && r.size+n > r.opts.MaxSize {
+		return true
+	}
+	if r.opts.Interval > 0 {
+		now := time.Now()
+		return !now.Truncate(r.opts.Interval).Equal(r.started.Truncate(r.opts.Interval))
+	}
+	return false
+}
+
+// Rotate closes the current file, moves it aside as the most recent segment
+// and starts a new, empty file.
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return &PathError{Op: "rotate", Path: r.File.Path, Err: os.ErrClosed}
+	}
+	return r.rotate()
+}
+
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return newPathError("rotate", r.File.Path, err)
+	}
+	r.file = nil
+
+	r.segMu.Lock()
+	segment, err := r.nextSegment()
+	if err == nil {
+		err = os.Rename(r.File.Path, segment)
+	}
+	renamed := err == nil
+
+	var compress *os.File
+	if renamed && r.opts.Compress {
+		// opened right away, as the segment may be shifted before it is
+		// compressed
+		var openErr error
+		if compress, openErr = os.Open(segment); openErr != nil {
+			r.deferError(newPathError("compress", segment, openErr))
+		}
+	}
+	var pruneErr error
+	if renamed {
+		pruneErr = r.prune()
+	}
+	r.segMu.Unlock()
+
+	// keep writing to a file even if moving the old one aside failed
+	if openErr := r.open(); err == nil {
+		err = openErr
+	}
+	if err != nil {
+		return newPathError("rotate", r.File.Path, err)
+	}
+
+	if compress != nil {
+		r.queueCompression(compress)
+	}
+	return pruneErr
+}
+
+// queueCompression queues the segment open as src for compression,
+// starting the background goroutine if it isn't running.
+func (r *RotatingFile) queueCompression(src *os.File) {
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+
+	r.queue = append(r.queue, src)
+	if !r.compressing {
+		r.compressing = true
+		go r.compressQueued()
+	}
+}
+
+// compressQueued compresses the queued segments until the queue is empty.
+func (r *RotatingFile) compressQueued() {
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+
+	for len(r.queue) > 0 {
+		src := r.queue[0]
+		r.queue = r.queue[1:]
+
+		r.queueMu.Unlock()
+		r.deferError(r.compressSegment(src))
+		r.queueMu.Lock()
+	}
+
+	r.compressing = false
+	r.queueIdle.Broadcast()
+}
+
+// waitCompressed waits until the segments queued so far are compressed.
+func (r *RotatingFile) waitCompressed() {
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+
+	for r.compressing {
+		r.queueIdle.Wait()
+	}
+}
+
+// deferError records an error to be returned by Close.
+func (r *RotatingFile) deferError(err error) {
+	if err == nil {
+		return
+	}
+	r.errMu.Lock()
+	r.errs = append(r.errs, err)
+	r.errMu.Unlock()
+}
+
+// nextSegment returns the path the current file is rotated to. With
+// RotateIndex the existing segments are shifted up by one to make room.
+func (r *RotatingFile) nextSegment() (string, error) {
+	if r.opts.Naming == RotateTimestamp {
+		now := time.Now().UTC()
+		for {
+			segment := r.File.Path + "." + now.Format(rotateTimeFormat)
+			if !r.segmentExists(segment) {
+				return segment, nil
+			}
+			now = now.Add(time.Nanosecond)
+		}
+	}
+
+	segments, err := r.segments()
+	if err != nil {
+		return "", err
+	}
+
+	// shift from the oldest so no segment is overwritten
+	for i := len(segments) - 1; i >= 0; i-- {
+		s := segments[i]
+		name := r.File.Path + "." + strconv.Itoa(s.index+1)
+		if strings.HasSuffix(s.path, ".gz") {
+			name += ".gz"
+		}
+		if err := os.Rename(s.path, name); err != nil {
+			return "", err
+		}
+	}
+	return r.File.Path + ".1", nil
+}
+
+func (r *RotatingFile) segmentExists(segment string) bool {
+	for _, p := range []string{segment, segment + ".gz"} {
+		if _, err := os.Lstat(p); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// rotatedSegment is a segment found next to the current file.
+type rotatedSegment struct {
+	path  string
+	index int
+	stamp string
+}
+
+// segments returns the rotated segments, most recent first.
+func (r *RotatingFile) segments() ([]rotatedSegment, error) {
+	entries, err := os.ReadDir(path.Dir(r.File.Path))
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := path.Base(r.File.Path) + "."
+
+	var segments []rotatedSegment
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		suffix := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz")
+		segment := rotatedSegment{path: path.Join(path.Dir(r.File.Path), name)}
+
+		if r.opts.Naming == RotateTimestamp {
+			if _, err := time.Parse(rotateTimeFormat, suffix); err != nil {
+				continue
+			}
+			segment.stamp = suffix
+		} else {
+			index, err := strconv.Atoi(suffix)
+			if err != nil || index < 1 {
+				continue
+			}
+			segment.index = index
+		}
+		segments = append(segments, segment)
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		if segments[i].stamp != segments[j].stamp {
+			return segments[i].stamp > segments[j].stamp
+		}
+		return segments[i].index < segments[j].index
+	})
+	return segments, nil
+}
+
+// Segments returns the rotated segments of the file, most recent first,
+// once the segments rotated so far are compressed.
+func (r *RotatingFile) Segments() ([]*File, error) {
+	r.waitCompressed()
+
+	r.segMu.Lock()
+	defer r.segMu.Unlock()
+
+	segments, err := r.segments()
+	if err != nil {
+		return nil, newPathError("segments", r.File.Path, err)
+	}
+
+	files := make([]*File, len(segments))
+	for i, s := range segments {
+		files[i] = NewFile(s.path)
+	}
+	return files, nil
+}
+
+// prune removes the segments exceeding MaxCount or older than MaxAge. It is
+// called with segMu held.
+func (r *RotatingFile) prune() error {
+	if r.opts.MaxCount <= 0 && r.opts.MaxAge <= 0 {
+		return nil
+	}
+
+	segments, err := r.segments()
+	if err != nil {
+		return newPathError("rotate", r.File.Path, err)
+	}
+
+	var errs []error
+	for i, s := range segments {
+		remove := r.opts.MaxCount > 0 && i >= r.opts.MaxCount
+		if !remove && r.opts.MaxAge > 0 {
+			info, err := os.Stat(s.path)
+			remove = err == nil && time.Since(info.ModTime()) > r.opts.MaxAge
+		}
+		if remove {
+			if err := os.Remove(s.path); err != nil {
+				errs = append(errs, newPathError("rotate", s.path, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// compressSegment gzips the segment open as src and replaces it with the
+// compressed copy, named after it with a ".gz" extension and keeping its
+// modification time. The segment may have been shifted while it was being
+// compressed, and is left alone if it was pruned.
+func (r *RotatingFile) compressSegment(src *os.File) error {
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return newPathError("compress", src.Name(), err)
+	}
+
+	tmp, err := os.CreateTemp(path.Dir(r.File.Path), "."+path.Base(r.File.Path)+".*.gz.tmp")
+	if err != nil {
+		return newPathError("compress", src.Name(), err)
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := gzip.NewWriter(tmp)
+	zw.Name = path.Base(src.Name())
+	zw.ModTime = info.ModTime()
+	_, err = io.Copy(zw, src)
+	if err == nil {
+		err = zw.Close()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), info.Mode().Perm())
+	}
+	if err != nil {
+		return newPathError("compress", src.Name(), err)
+	}
+	os.Chtimes(tmp.Name(), info.ModTime(), info.ModTime())
+
+	r.segMu.Lock()
+	defer r.segMu.Unlock()
+
+	segment, err := r.findSegment(info)
+	if err != nil || segment == "" {
+		return newPathError("compress", src.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), segment+".gz"); err != nil {
+		return newPathError("compress", segment, err)
+	}
+	return newPathError("compress", segment, os.Remove(segment))
+}
+
+// findSegment returns the path of the uncompressed segment that is the
+// same file as info, or an empty path if there is none. It is called with
+// segMu held.
+func (r *RotatingFile) findSegment(info os.FileInfo) (string, error) {
+	segments, err := r.segments()
+	if err != nil {
+		return "", err
+	}
+	for _, s := range segments {
+		if strings.HasSuffix(s.path, ".gz") {
+			continue
+		}
+		if other, err := os.Lstat(s.path); err == nil && os.SameFile(info, other) {
+			return s.path, nil
+		}
+	}
+	return "", nil
+}
+
+// Close closes the current file and waits for the compression of the
+// rotated segments. It returns the errors of rotations triggered by writes that
+// didn't fail the write itself. Writes after Close fail.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := newPathError("close", r.File.Path, r.file.Close())
+	r.file = nil
+	r.waitCompressed()
+
+	r.errMu.Lock()
+	defer r.errMu.Unlock()
+	err = errors.Join(append(r.errs, err)...)
+	r.errs = nil
+	return err
+}
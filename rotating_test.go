@@ -0,0 +1,282 @@
+package filic_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func segmentNames(t *testing.T, r *filic.RotatingFile) []string {
+	t.Helper()
+
+	segments, err := r.Segments()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, len(segments))
+	for i, s := range segments {
+		names[i] = path.Base(s.Path)
+	}
+	return names
+}
+
+func TestRotatingFileBySize(t *testing.T) {
+	cleanup()
+
+	dir := filic.NewDirectory(getTempDirPath())
+	dir.Create()
+
+	r, err := filic.NewRotatingFile(filic.NewFile(dir.Join("app.log")), filic.RotatingFileOptions{
+		MaxSize:  10,
+		MaxCount: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for i := range 4 {
+		fmt.Fprintf(r, "line %v\n", i)
+	}
+
+	expectNames(t, []string{"app.log.1", "app.log.2"}, segmentNames(t, r))
+
+	content, _ := filic.NewFile(dir.Join("app.log")).ReadString()
+	if content != "line 3\n" {
+		t.Errorf("Expected current file to hold the last line, got %q", content)
+	}
+
+	content, _ = filic.NewFile(dir.Join("app.log.1")).ReadString()
+	if content != "line 2\n" {
+		t.Errorf("Expected app.log.1 to be the most recent segment, got %q", content)
+	}
+
+	cleanup()
+}
+
+func TestRotatingFileCompressTimestamp(t *testing.T) {
+	cleanup()
+
+	dir := filic.NewDirectory(getTempDirPath())
+	dir.Create()
+
+	r, err := filic.NewRotatingFile(filic.NewFile(dir.Join("app.log")), filic.RotatingFileOptions{
+		Naming:   filic.RotateTimestamp,
+		Compress: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	r.Write([]byte("first\n"))
+	if err := r.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	r.Write([]byte("second\n"))
+	r.Rotate()
+
+	names := segmentNames(t, r)
+	if len(names) != 2 {
+		t.Fatalf("Expected 2 segments, got %v", names)
+	}
+
+	f, err := os.Open(dir.Join(names[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if !strings.HasSuffix(names[1], ".gz") {
+		t.Errorf("Expected compressed segment, got %v", names[1])
+	}
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(zr)
+	if string(data) != "first\n" {
+		t.Errorf("Expected oldest segment to hold %q, got %q", "first\n", data)
+	}
+
+	cleanup()
+}
+
+func TestRotatingFileCompressFailure(t *testing.T) {
+	cleanup()
+
+	dir := filic.NewDirectory(getTempDirPath())
+	dir.Create()
+
+	// a directory in the way of the compressed segment
+	os.MkdirAll(dir.Join("app.log.1.gz/blocked"), 0755)
+
+	r, err := filic.NewRotatingFile(filic.NewFile(dir.Join("app.log")), filic.RotatingFileOptions{
+		MaxSize:  10,
+		Compress: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Write([]byte("first line\n"))
+	if n, err := r.Write([]byte("second\n")); err != nil || n != 7 {
+		t.Errorf("Expected the write to go through, got %d (%v)", n, err)
+	}
+
+	if err := r.Close(); err == nil {
+		t.Error("Expected Close to report the compression failure")
+	}
+
+	content, _ := filic.NewFile(dir.Join("app.log")).ReadString()
+	if content != "second\n" {
+		t.Errorf("Expected %q in the current file, got %q", "second\n", content)
+	}
+	content, _ = filic.NewFile(dir.Join("app.log.1")).ReadString()
+	if content != "first line\n" {
+		t.Errorf("Expected the segment to be kept uncompressed, got %q", content)
+	}
+
+	cleanup()
+}
+
+func TestRotatingFileWritesDuringCompression(t *testing.T) {
+	cleanup()
+
+	dir := filic.NewDirectory(getTempDirPath())
+	dir.Create()
+
+	r, err := filic.NewRotatingFile(filic.NewFile(dir.Join("app.log")), filic.RotatingFileOptions{
+		Compress: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// random data is slow to compress
+	large := make([]byte, 16<<20)
+	rand.NewChaCha8([32]byte{}).Read(large)
+	r.Write(large)
+	r.Rotate()
+
+	// neither a rotation nor a write waits for the large segment
+	r.Write([]byte("small\n"))
+	if err := r.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := r.Write([]byte("current\n")); err != nil || n != 8 {
+		t.Errorf("Expected the write to go through, got %d (%v)", n, err)
+	}
+	if !filic.NewEntity(dir.Join("app.log.2")).Exists() {
+		t.Error("Expected the large segment to still be compressing")
+	}
+
+	expectNames(t, []string{"app.log.1.gz", "app.log.2.gz"}, segmentNames(t, r))
+
+	f, err := os.Open(dir.Join("app.log.2.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(zr)
+	if !bytes.Equal(data, large) {
+		t.Errorf("Expected the shifted segment to hold the large write, got %d bytes", len(data))
+	}
+
+	cleanup()
+}
+
+func TestRotatingFileByInterval(t *testing.T) {
+	cleanup()
+
+	dir := filic.NewDirectory(getTempDirPath())
+	dir.Create()
+
+	r, err := filic.NewRotatingFile(filic.NewFile(dir.Join("app.log")), filic.RotatingFileOptions{
+		Interval: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	r.Write([]byte("before\n"))
+	time.Sleep(100 * time.Millisecond)
+	r.Write([]byte("after\n"))
+
+	expectNames(t, []string{"app.log.1"}, segmentNames(t, r))
+
+	cleanup()
+}
+
+func TestRotatingFileConcurrentWrites(t *testing.T) {
+	cleanup()
+
+	dir := filic.NewDirectory(getTempDirPath())
+	dir.Create()
+
+	r, err := filic.NewRotatingFile(filic.NewFile(dir.Join("app.log")), filic.RotatingFileOptions{
+		MaxSize: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				fmt.Fprintf(r, "writer %v\n", i)
+			}
+		}()
+	}
+	wg.Wait()
+	r.Close()
+
+	segments, _ := r.Segments()
+	files := append(segments, filic.NewFile(dir.Join("app.log")))
+
+	lines := 0
+	for _, f := range files {
+		content, err := f.ReadString()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+			if !strings.HasPrefix(line, "writer ") {
+				t.Errorf("Unexpected interleaved line %q", line)
+			}
+			lines++
+		}
+	}
+
+	if lines != 160 {
+		t.Errorf("Expected 160 lines across segments, got %v", lines)
+	}
+
+	if _, err := r.Write([]byte("x")); err == nil {
+		t.Error("Expected an error writing after Close")
+	}
+
+	cleanup()
+}
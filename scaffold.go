@@ -0,0 +1,415 @@
+package filic
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"text/template"
+)
+
+// Scaffold describes a directory tree to create with Directory.Scaffold or
+// to check with Directory.VerifyScaffold. It can be written as a Go value or
+// decoded from JSON with ParseScaffold.
+type Scaffold struct {
+	Entries []ScaffoldEntry `json:"entries"`
+}
+
+// ScaffoldEntry describes one entry of a Scaffold.
+type ScaffoldEntry struct {
+	// Name is the path of the entry relative to its parent. It may contain
+	// slashes, in which case missing intermediate directories are created.
+	Name string `json:"name"`
+	// Type is "file", "dir" or "symlink". When empty, entries with children
+	// are directories and other entries are files.
+	Type string `json:"type,omitempty"`
+	// Content is the content of a file.
+	Content string `json:"content,omitempty"`
+	// Template, if set, is a text/template rendered with
+	// ScaffoldOptions.Data to produce the content of a file.
+	Template string `json:"template,omitempty"`
+	// Target is the target of a symbolic link.
+	Target string `json:"target,omitempty"`
+	// Mode is the permission bits of a file or directory, 0644 and 0755 by
+	// default. In JSON it is written as an octal string such as "0755".
+	Mode fs.FileMode `json:"mode,omitempty"`
+	// Children are the entries inside a directory.
+	Children []ScaffoldEntry `json:"children,omitempty"`
+}
+
+// scaffoldEntryJSON is the JSON form of a ScaffoldEntry, with the mode as
+// an octal string.
+type scaffoldEntryJSON struct {
+	Name     string          `json:"name"`
+	Type     string          `json:"type,omitempty"`
+	Content  string          `json:"content,omitempty"`
+	Template string          `json:"template,omitempty"`
+	Target   string          `json:"target,omitempty"`
+	Mode     string          `json:"mode,omitempty"`
+	Children []ScaffoldEntry `json:"children,omitempty"`
+}
+
+// MarshalJSON encodes the entry with its mode as an octal string.
+func (e ScaffoldEntry) MarshalJSON() ([]byte, error) {
+	j := scaffoldEntryJSON{
+		Name: e.Name, Type: e.Type, Content: e.Content, Template: e.Template,
+		Target: e.Target, Children: e.Children,
+	}
+	if e.Mode != 0 {
+		j.Mode = fmt.Sprintf("%04o", uint32(e.Mode.Perm()))
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes an entry whose mode is an octal string.
+func (e *ScaffoldEntry) UnmarshalJSON(data []byte) error {
+	var j scaffoldEntryJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	var mode uint64
+	if j.Mode != "" {
+		var err error
+		if mode, err = strconv.ParseUint(j.Mode, 8, 32); err != nil || mode > uint64(fs.ModePerm) {
+			return fmt.Errorf("invalid mode %q for %q", j.Mode, j.Name)
+		}
+	}
+
+	*e = ScaffoldEntry{
+		Name: j.Name, Type: j.Type, Content: j.Content, Template: j.Template,
+		Target: j.Target, Mode: fs.FileMode(mode), Children: j.Children,
+	}
+	return nil
+}
+
+// ParseScaffold decodes a Scaffold from JSON.
+func ParseScaffold(data []byte) (*Scaffold, error) {
+	var s Scaffold
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ScaffoldOptions configures Scaffold and VerifyScaffold.
+type ScaffoldOptions struct {
+	// Data is passed to the templates of file entries.
+	Data any
+	// Overwrite replaces existing files and symbolic links that differ from
+	// the scaffold. Otherwise they make Scaffold fail with ErrExist.
+	Overwrite bool
+	// Strict makes VerifyScaffold also report entries that exist in the
+	// scaffold's directories but are not described by it.
+	Strict bool
+}
+
+// DriftKind describes how an entry differs from its scaffold.
+type DriftKind int
+
+const (
+	// DriftMissing marks an entry of the scaffold that doesn't exist.
+	DriftMissing DriftKind = iota
+	// DriftType marks an entry of another type than described.
+	DriftType
+	// DriftContent marks a file whose content differs.
+	DriftContent
+	// DriftMode marks an entry whose permissions differ.
+	DriftMode
+	// DriftTarget marks a symbolic link pointing somewhere else.
+	DriftTarget
+	// DriftExtra marks an entry not described by the scaffold, reported
+	// in strict mode only.
+	DriftExtra
+)
+
+var driftKindNames = []string{"missing", "type", "content", "mode", "target", "extra"}
+
+// String returns the name of the kind, such as "missing".
+func (k DriftKind) String() string {
+	if int(k) < len(driftKindNames) {
+		return driftKindNames[k]
+	}
+	return fmt.Sprintf("DriftKind(%d)", int(k))
+}
+
+// MarshalText encodes the kind as its name, so it reads well in JSON.
+func (k DriftKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Drift is one difference between a directory and a scaffold.
+type Drift struct {
+	// Path is the path of the entry relative to the directory.
+	Path string    `json:"path"`
+	Kind DriftKind `json:"kind"`
+}
+
+// scaffoldItem is an entry of a scaffold with its resolved path and type.
+type scaffoldItem struct {
+	rel   string
+	path  string
+	entry ScaffoldEntry
+}
+
+// flatten resolves the entries of s below d, parents first.
+func (s *Scaffold) flatten(op string, d *Directory) ([]scaffoldItem, error) {
+	var items []scaffoldItem
+
+	var add func(parent string, entries []ScaffoldEntry) error
+	add = func(parent string, entries []ScaffoldEntry) error {
+		for _, entry := range entries {
+			rel := path.Clean(path.Join(parent, entry.Name))
+			p, err := d.child(op, rel)
+			if err != nil {
+				return err
+			}
+			if entry.Name == "" || rel == "." {
+				return &PathError{Op: op, Path: p, Err: errors.New("scaffold entry without a name")}
+			}
+
+			if entry.Type == "" {
+				entry.Type = "file"
+				if len(entry.Children) > 0 {
+					entry.Type = "dir"
+				}
+			}
+			switch entry.Type {
+			case "file", "dir", "symlink":
+			default:
+				return &PathError{Op: op, Path: p, Err: fmt.Errorf("unknown scaffold entry type %q", entry.Type)}
+			}
+			if entry.Type != "dir" && len(entry.Children) > 0 {
+				return &PathError{Op: op, Path: p, Err: ErrNotDirectory}
+			}
+
+			items = append(items, scaffoldItem{rel: rel, path: p, entry: entry})
+			if err := add(rel, entry.Children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return items, add(".", s.Entries)
+}
+
+// content returns the content of a file entry, rendering its template.
+func (item scaffoldItem) content(data any) ([]byte, error) {
+	if item.entry.Template == "" {
+		return []byte(item.entry.Content), nil
+	}
+
+	tmpl, err := template.New(item.rel).Option("missingkey=error").Parse(item.entry.Template)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// mode returns the permissions of the entry, applying the defaults.
+func (item scaffoldItem) mode() fs.FileMode {
+	if item.entry.Mode != 0 {
+		return item.entry.Mode.Perm()
+	}
+	if item.entry.Type == "dir" {
+		return 0755
+	}
+	return 0644
+}
+
+// Scaffold creates the tree described by s below d. Entries that already
+// exist as described are left alone, so scaffolding is idempotent, and keep
+// their permissions unless the entry sets a Mode. Existing files and
+// symbolic links that differ are replaced only with opts.Overwrite; files
+// are written atomically.
+func (d *Directory) Scaffold(s *Scaffold, opts ScaffoldOptions) error {
+	items, err := s.flatten("scaffold", d)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(d.Path, 0755); err != nil {
+		return newPathError("scaffold", d.Path, err)
+	}
+
+	var dirs []scaffoldItem
+	for _, item := range items {
+		if err := os.MkdirAll(path.Dir(item.path), 0755); err != nil {
+			return newPathError("scaffold", item.path, err)
+		}
+
+		info, statErr := os.Lstat(item.path)
+		exists := statErr == nil
+		if exists && entityType(info) != item.entry.Type {
+			return &PathError{Op: "scaffold", Path: item.path, Err: ErrExist}
+		}
+
+		switch item.entry.Type {
+		case "dir":
+			if !exists {
+				if err := os.Mkdir(item.path, 0755); err != nil {
+					return newPathError("scaffold", item.path, err)
+				}
+			}
+			if !exists || item.entry.Mode != 0 {
+				dirs = append(dirs, item)
+			}
+			continue
+
+		case "symlink":
+			if exists {
+				target, err := os.Readlink(item.path)
+				if err != nil {
+					return newPathError("scaffold", item.path, err)
+				}
+				if target == item.entry.Target {
+					continue
+				}
+				if !opts.Overwrite {
+					return &PathError{Op: "scaffold", Path: item.path, Err: ErrExist}
+				}
+				os.Remove(item.path)
+			}
+			if err := os.Symlink(item.entry.Target, item.path); err != nil {
+				return newPathError("scaffold", item.path, err)
+			}
+			continue
+
+		case "file":
+			content, err := item.content(opts.Data)
+			if err != nil {
+				return &PathError{Op: "scaffold", Path: item.path, Err: err}
+			}
+			if exists {
+				same, err := contentEquals(item.path, content)
+				if err != nil {
+					return newPathError("scaffold", item.path, err)
+				}
+				if !same && !opts.Overwrite {
+					return &PathError{Op: "scaffold", Path: item.path, Err: ErrExist}
+				}
+				if same && item.entry.Mode == 0 {
+					continue
+				}
+				if same {
+					break
+				}
+			}
+			err = atomicWriteFile(item.path, item.mode(), func(w io.Writer) error {
+				_, err := w.Write(content)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := os.Chmod(item.path, item.mode()); err != nil {
+			return newPathError("scaffold", item.path, err)
+		}
+	}
+
+	// directory modes last, deepest first, in case they drop write access
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i].path, dirs[i].mode()); err != nil {
+			return newPathError("scaffold", dirs[i].path, err)
+		}
+	}
+	return nil
+}
+
+// VerifyScaffold checks that the tree below d matches s and reports every
+// difference, ordered by path. An empty result means the tree matches.
+// Modes are only checked for entries that set one.
+func (d *Directory) VerifyScaffold(s *Scaffold, opts ScaffoldOptions) ([]Drift, error) {
+	items, err := s.flatten("verify", d)
+	if err != nil {
+		return nil, err
+	}
+
+	var drift []Drift
+	described := map[string]bool{}
+	dirs := map[string]bool{".": true}
+
+	for _, item := range items {
+		described[item.rel] = true
+		for dir := path.Dir(item.rel); dir != "."; dir = path.Dir(dir) {
+			dirs[dir] = true
+			described[dir] = true
+		}
+		if item.entry.Type == "dir" {
+			dirs[item.rel] = true
+		}
+
+		info, err := os.Lstat(item.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			drift = append(drift, Drift{Path: item.rel, Kind: DriftMissing})
+			continue
+		}
+		if err != nil {
+			return nil, newPathError("verify", item.path, err)
+		}
+		if entityType(info) != item.entry.Type {
+			drift = append(drift, Drift{Path: item.rel, Kind: DriftType})
+			continue
+		}
+
+		switch item.entry.Type {
+		case "symlink":
+			target, err := os.Readlink(item.path)
+			if err != nil {
+				return nil, newPathError("verify", item.path, err)
+			}
+			if target != item.entry.Target {
+				drift = append(drift, Drift{Path: item.rel, Kind: DriftTarget})
+			}
+			continue
+
+		case "file":
+			content, err := item.content(opts.Data)
+			if err != nil {
+				return nil, &PathError{Op: "verify", Path: item.path, Err: err}
+			}
+			same, err := contentEquals(item.path, content)
+			if err != nil {
+				return nil, newPathError("verify", item.path, err)
+			}
+			if !same {
+				drift = append(drift, Drift{Path: item.rel, Kind: DriftContent})
+			}
+		}
+
+		if item.entry.Mode != 0 && info.Mode().Perm() != item.entry.Mode.Perm() {
+			drift = append(drift, Drift{Path: item.rel, Kind: DriftMode})
+		}
+	}
+
+	if opts.Strict {
+		for dir := range dirs {
+			entries, err := os.ReadDir(d.Join(dir))
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				rel := path.Join(dir, entry.Name())
+				if !described[rel] {
+					drift = append(drift, Drift{Path: rel, Kind: DriftExtra})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(drift, func(i, j int) bool { return drift[i].Path < drift[j].Path })
+	return drift, nil
+}
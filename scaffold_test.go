@@ -0,0 +1,142 @@
+package filic_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func driftNames(drift []filic.Drift) []string {
+	var names []string
+	for _, d := range drift {
+		names = append(names, d.Kind.String()+" "+d.Path)
+	}
+	return names
+}
+
+func TestScaffoldFromJSON(t *testing.T) {
+	cleanup()
+
+	spec, err := filic.ParseScaffold([]byte(`{
+		"entries": [
+			{"name": "README.md", "template": "# {{.Name}}\n"},
+			{"name": "cmd", "children": [
+				{"name": "main.go", "content": "package main\n"}
+			]},
+			{"name": "scripts/build.sh", "content": "#!/bin/sh\n", "mode": "0755"},
+			{"name": "logs", "type": "dir"},
+			{"name": "latest", "type": "symlink", "target": "README.md"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filic.NewDirectory(getTempDirPath())
+	opts := filic.ScaffoldOptions{Data: map[string]string{"Name": "demo"}}
+
+	if err := dir.Scaffold(spec, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := filic.NewFile(dir.Join("README.md")).ReadString()
+	if content != "# demo\n" {
+		t.Errorf("Expected rendered template, got %q", content)
+	}
+
+	info, err := os.Stat(dir.Join("scripts/build.sh"))
+	if err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("Expected mode 0755, got %v (%v)", info, err)
+	}
+
+	if info, err := os.Stat(dir.Join("logs")); err != nil || !info.IsDir() {
+		t.Errorf("Expected logs to be a directory (%v)", err)
+	}
+
+	drift, err := dir.VerifyScaffold(spec, opts)
+	if err != nil || len(drift) != 0 {
+		t.Errorf("Expected no drift right after scaffolding, got %v (%v)", drift, err)
+	}
+
+	// scaffolding again is a no-op, and leaves modes that aren't set alone
+	os.Chmod(dir.Join("README.md"), 0600)
+	os.Chmod(dir.Join("logs"), 0700)
+	os.Chmod(dir.Join("scripts/build.sh"), 0700)
+	if err := dir.Scaffold(spec, opts); err != nil {
+		t.Errorf("Expected scaffolding to be idempotent, got %v", err)
+	}
+
+	for name, expected := range map[string]os.FileMode{
+		"README.md":        0600,
+		"logs":             0700,
+		"scripts/build.sh": 0755,
+	} {
+		info, err := os.Stat(dir.Join(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != expected {
+			t.Errorf("Expected mode %v for %v, got %v", expected, name, info.Mode().Perm())
+		}
+	}
+
+	cleanup()
+}
+
+func TestVerifyScaffoldDrift(t *testing.T) {
+	cleanup()
+
+	spec := &filic.Scaffold{Entries: []filic.ScaffoldEntry{
+		{Name: "config.json", Content: "{}"},
+		{Name: "bin", Type: "dir", Mode: 0755},
+		{Name: "link", Type: "symlink", Target: "config.json"},
+		{Name: "data", Type: "dir", Children: []filic.ScaffoldEntry{
+			{Name: "seed.csv", Content: "a,b\n"},
+		}},
+	}}
+
+	dir := filic.NewDirectory(getTempDirPath())
+	if err := dir.Scaffold(spec, filic.ScaffoldOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	filic.NewFile(dir.Join("config.json")).Write([]byte(`{"changed": true}`))
+	os.Chmod(dir.Join("bin"), 0700)
+	os.Remove(dir.Join("link"))
+	os.Symlink("elsewhere", dir.Join("link"))
+	os.Remove(dir.Join("data/seed.csv"))
+	os.WriteFile(dir.Join("data/extra.txt"), []byte("x"), 0644)
+
+	drift, err := dir.VerifyScaffold(spec, filic.ScaffoldOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNames(t, []string{
+		"mode bin", "content config.json", "extra data/extra.txt", "missing data/seed.csv", "target link",
+	}, driftNames(drift))
+
+	if err := dir.Scaffold(spec, filic.ScaffoldOptions{}); !errors.Is(err, filic.ErrExist) {
+		t.Errorf("Expected ErrExist without Overwrite, got %v", err)
+	}
+
+	if err := dir.Scaffold(spec, filic.ScaffoldOptions{Overwrite: true}); err != nil {
+		t.Fatal(err)
+	}
+	drift, _ = dir.VerifyScaffold(spec, filic.ScaffoldOptions{})
+	if len(drift) != 0 {
+		t.Errorf("Expected no drift after overwriting, got %v", driftNames(drift))
+	}
+
+	cleanup()
+}
+
+func TestScaffoldOutsideRoot(t *testing.T) {
+	spec := &filic.Scaffold{Entries: []filic.ScaffoldEntry{{Name: "../escape.txt"}}}
+
+	err := filic.NewDirectory(getTempDirPath()).Scaffold(spec, filic.ScaffoldOptions{})
+	if !errors.Is(err, filic.ErrOutsideRoot) {
+		t.Errorf("Expected ErrOutsideRoot, got %v", err)
+	}
+}
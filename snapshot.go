@@ -0,0 +1,467 @@
+package filic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SnapshotStore keeps snapshots of directory trees. File contents are kept
+// in a CAS in its "blobs" directory, so content shared between files or
+// snapshots is stored once, and every snapshot is recorded by a manifest,
+// itself a blob, referenced from its "snapshots" directory.
+type SnapshotStore struct {
+	Dir *Directory
+
+	blobs *CAS
+}
+
+// Snapshot records a directory tree: the type, mode and modification time
+// of every entry, the content of files and the target of symbolic links.
+type Snapshot struct {
+	// ID identifies the snapshot in its store. It is the digest of its
+	// manifest.
+	ID string `json:"-"`
+	// Root is the path of the directory the snapshot was taken of.
+	Root    string          `json:"root"`
+	Created time.Time       `json:"created"`
+	Entries []SnapshotEntry `json:"entries"`
+	// Ignore holds the ignore rules the snapshot was taken with. Restore
+	// leaves the paths they match alone.
+	Ignore *IgnoreMatcher `json:"ignore,omitempty"`
+
+	store *SnapshotStore
+}
+
+// SnapshotEntry is one entry of a Snapshot.
+type SnapshotEntry struct {
+	// Path is the slash separated path of the entry relative to the root.
+	Path string `json:"path"`
+	// Type is "file", "dir" or "symlink".
+	Type    string      `json:"type"`
+	Mode    fs.FileMode `json:"mode"`
+	ModTime time.Time   `json:"modTime"`
+	Size    int64       `json:"size,omitempty"`
+	// Digest is the digest of a file's content in the store's CAS.
+	Digest string `json:"digest,omitempty"`
+	// Target is the target of a symbolic link.
+	Target string `json:"target,omitempty"`
+}
+
+// SnapshotOptions configures Snapshot.
+type SnapshotOptions struct {
+	// Ignore, if set, leaves matching entries out of the snapshot.
+	Ignore *IgnoreMatcher
+	// Parent, if set, is an earlier snapshot of the same directory. Files
+	// whose size and modification time didn't change since the parent are
+	// not read again, which makes taking the snapshot incremental.
+	Parent *Snapshot
+}
+
+// NewSnapshotStore returns the snapshot store kept in dir. The directory is
+// created when the first snapshot is taken.
+func NewSnapshotStore(dir *Directory) *SnapshotStore {
+	return &SnapshotStore{Dir: dir, blobs: NewCAS(NewDirectory(dir.Join("blobs")))}
+}
+
+func (s *SnapshotStore) refsDir() string { return s.Dir.Join("snapshots") }
+
+// within returns the path of the store relative to d, and whether the store
+// lives below d at all.
+func (s *SnapshotStore) within(d *Directory) (string, bool) {
+	storePath, err1 := filepath.Abs(s.Dir.Path)
+	dirPath, err2 := filepath.Abs(d.Path)
+	if err1 != nil || err2 != nil {
+		return "", false
+	}
+
+	rel, err := filepath.Rel(dirPath, storePath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// Snapshot records the tree below d into store. Only content that is not
+// already in the store is copied into it. Hard links are recorded as
+// separate files, and entries other than files, directories and symbolic
+// links are skipped, as is the store itself when it lives below d.
+func (d *Directory) Snapshot(store *SnapshotStore, opts SnapshotOptions) (*Snapshot, error) {
+	return d.SnapshotContext(context.Background(), store, opts)
+}
+
+// SnapshotContext is like Snapshot but stops and returns the context's error
+// once ctx is cancelled.
+func (d *Directory) SnapshotContext(ctx context.Context, store *SnapshotStore, opts SnapshotOptions) (*Snapshot, error) {
+	previous := map[string]SnapshotEntry{}
+	if opts.Parent != nil {
+		for _, entry := range opts.Parent.Entries {
+			previous[entry.Path] = entry
+		}
+	}
+
+	snap := &Snapshot{Root: d.Path, Created: time.Now().UTC(), Ignore: opts.Ignore, store: store}
+	storeRel, storeInside := store.within(d)
+
+	err := d.WalkWithOptions(ctx, WalkOptions{Ignore: opts.Ignore}, func(entity *Entity) error {
+		if storeInside && relativePath(d.Path, entity.Path) == storeRel {
+			return SkipDir
+		}
+
+		info, err := entity.lstat()
+		if err != nil {
+			return newPathError("snapshot", entity.Path, err)
+		}
+
+		entry := SnapshotEntry{
+			Path:    relativePath(d.Path, entity.Path),
+			Type:    entityType(info),
+			Mode:    info.Mode(),
+			ModTime: info.ModTime(),
+		}
+
+		switch entry.Type {
+		case "dir":
+		case "symlink":
+			if entry.Target, err = os.Readlink(entity.Path); err != nil {
+				return newPathError("snapshot", entity.Path, err)
+			}
+		case "file":
+			entry.Size = info.Size()
+			if prev, ok := previous[entry.Path]; ok && prev.Type == "file" &&
+				prev.Size == entry.Size && prev.ModTime.Equal(entry.ModTime) && store.blobs.Has(prev.Digest) {
+				entry.Digest = prev.Digest
+				break
+			}
+			if entry.Digest, err = store.putFile(entity.Path); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+
+		snap.Entries = append(snap.Entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := store.save(snap); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// putFile stores the content of the file at p in the store's CAS.
+func (s *SnapshotStore) putFile(p string) (string, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return "", newPathError("snapshot", p, err)
+	}
+	defer file.Close()
+
+	return s.blobs.Put(file)
+}
+
+// save stores the manifest of snap and records it as a snapshot.
+func (s *SnapshotStore) save(snap *Snapshot) error {
+	manifest, err := json.Marshal(snap)
+	if err != nil {
+		return newPathError("snapshot", s.Dir.Path, err)
+	}
+
+	id, err := s.blobs.Put(bytes.NewReader(manifest))
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.refsDir(), 0755); err != nil {
+		return newPathError("snapshot", s.refsDir(), err)
+	}
+	ref := path.Join(s.refsDir(), id)
+	if err := os.WriteFile(ref, nil, 0644); err != nil {
+		return newPathError("snapshot", ref, err)
+	}
+
+	snap.ID = id
+	return nil
+}
+
+// Get returns the snapshot with the given ID.
+func (s *SnapshotStore) Get(id string) (*Snapshot, error) {
+	ref := path.Join(s.refsDir(), id)
+	if !validDigest(id) {
+		return nil, &PathError{Op: "snapshot", Path: ref, Err: ErrInvalidDigest}
+	}
+	if _, err := os.Stat(ref); err != nil {
+		return nil, newPathError("snapshot", ref, err)
+	}
+
+	r, err := s.blobs.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	manifest, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &Snapshot{ID: id, store: s}
+	if err := json.Unmarshal(manifest, snap); err != nil {
+		return nil, &PathError{Op: "snapshot", Path: ref, Err: err}
+	}
+	return snap, nil
+}
+
+// List returns the snapshots in the store, oldest first.
+func (s *SnapshotStore) List() ([]*Snapshot, error) {
+	entries, err := os.ReadDir(s.refsDir())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, newPathError("list", s.refsDir(), err)
+	}
+
+	var snaps []*Snapshot
+	for _, entry := range entries {
+		if !validDigest(entry.Name()) {
+			continue
+		}
+		snap, err := s.Get(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		snaps = append(snaps, snap)
+	}
+
+	sort.SliceStable(snaps, func(i, j int) bool {
+		return snaps[i].Created.Before(snaps[j].Created)
+	})
+	return snaps, nil
+}
+
+// Delete removes the snapshot with the given ID from the store. Its content
+// is only freed by Prune, as it may be shared with other snapshots.
+func (s *SnapshotStore) Delete(id string) error {
+	ref := path.Join(s.refsDir(), id)
+	if !validDigest(id) {
+		return &PathError{Op: "delete", Path: ref, Err: ErrInvalidDigest}
+	}
+	if err := os.Remove(ref); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return newPathError("delete", ref, err)
+	}
+	return nil
+}
+
+// Prune removes the content no longer used by any snapshot of the store. It
+// must not run while a snapshot is being taken.
+func (s *SnapshotStore) Prune() (*GCReport, error) {
+	snaps, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var roots []string
+	for _, snap := range snaps {
+		roots = append(roots, snap.ID)
+		for _, entry := range snap.Entries {
+			if entry.Digest != "" {
+				roots = append(roots, entry.Digest)
+			}
+		}
+	}
+	return s.blobs.GC(roots, GCOptions{})
+}
+
+// Diff compares the snapshot, as the old side, with other, as the new side.
+// Files are compared by content digest and symbolic links by target.
+func (s *Snapshot) Diff(other *Snapshot) []DiffEntry {
+	oldEntries := make(map[string]SnapshotEntry, len(s.Entries))
+	newEntries := make(map[string]SnapshotEntry, len(other.Entries))
+
+	paths := make([]string, 0, len(s.Entries)+len(other.Entries))
+	for _, entry := range s.Entries {
+		oldEntries[entry.Path] = entry
+		paths = append(paths, entry.Path)
+	}
+	for _, entry := range other.Entries {
+		newEntries[entry.Path] = entry
+		if _, ok := oldEntries[entry.Path]; !ok {
+			paths = append(paths, entry.Path)
+		}
+	}
+	sort.Strings(paths)
+
+	var diff []DiffEntry
+	for _, rel := range paths {
+		oldEntry, inOld := oldEntries[rel]
+		newEntry, inNew := newEntries[rel]
+
+		entry := DiffEntry{Path: rel}
+		if inOld {
+			entry.OldType, entry.OldSize = oldEntry.Type, oldEntry.Size
+		}
+		if inNew {
+			entry.NewType, entry.NewSize = newEntry.Type, newEntry.Size
+		}
+
+		switch {
+		case !inOld:
+			entry.Kind = DiffAdded
+		case !inNew:
+			entry.Kind = DiffRemoved
+		case oldEntry.Type != newEntry.Type:
+			entry.Kind = DiffTypeChanged
+		case oldEntry.Digest != newEntry.Digest || oldEntry.Target != newEntry.Target:
+			entry.Kind = DiffModified
+		default:
+			continue
+		}
+		diff = append(diff, entry)
+	}
+	return diff
+}
+
+// Restore brings the tree below d back to the state recorded by snap:
+// entries that are not in the snapshot are removed, missing or changed
+// ones are recreated from the store, and modes and modification times are
+// reset. Files whose content already matches are left in place. Paths
+// matched by the ignore rules of the snapshot, and the store itself when it
+// lives below d, are never removed.
+func (d *Directory) Restore(snap *Snapshot) error {
+	return d.RestoreContext(context.Background(), snap)
+}
+
+// RestoreContext is like Restore but stops and returns the context's error
+// once ctx is cancelled.
+func (d *Directory) RestoreContext(ctx context.Context, snap *Snapshot) error {
+	if snap.store == nil {
+		return &PathError{Op: "restore", Path: d.Path, Err: errors.New("snapshot is not from a store")}
+	}
+
+	wanted := make(map[string]SnapshotEntry, len(snap.Entries))
+	for _, entry := range snap.Entries {
+		wanted[entry.Path] = entry
+	}
+
+	if err := os.MkdirAll(d.Path, 0755); err != nil {
+		return newPathError("restore", d.Path, err)
+	}
+
+	// remove what the snapshot doesn't have, or has with another type
+	existing, err := scanTree(ctx, d, snap.Ignore)
+	if err != nil {
+		return err
+	}
+	storeRel, storeInside := snap.store.within(d)
+
+	var extra []string
+	for rel, info := range existing {
+		if storeInside && (rel == storeRel || strings.HasPrefix(storeRel, rel+"/") || strings.HasPrefix(rel, storeRel+"/")) {
+			continue
+		}
+		if entry, ok := wanted[rel]; ok && entry.Type == entityType(info) {
+			continue
+		}
+		extra = append(extra, rel)
+	}
+
+	// deepest first, one entry at a time, so that a directory holding
+	// ignored entries is kept rather than removed with them
+	sort.Sort(sort.Reverse(sort.StringSlice(extra)))
+	for _, rel := range extra {
+		err := os.Remove(d.Join(rel))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) && !isDirNotEmpty(d.Join(rel)) {
+			return newPathError("restore", d.Join(rel), err)
+		}
+	}
+
+	// entries are sorted by path, so directories come before their contents
+	for _, entry := range snap.Entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := d.restoreEntry(ctx, snap.store, entry); err != nil {
+			return err
+		}
+	}
+
+	// modes and times last, deepest first, as creating entries changes the
+	// modification time of their directory and may need write permission
+	for i := len(snap.Entries) - 1; i >= 0; i-- {
+		entry := snap.Entries[i]
+		if entry.Type == "symlink" {
+			continue
+		}
+
+		p := d.Join(entry.Path)
+		if err := os.Chmod(p, entry.Mode&(fs.ModePerm|fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky)); err != nil {
+			return newPathError("restore", p, err)
+		}
+		if err := os.Chtimes(p, entry.ModTime, entry.ModTime); err != nil {
+			return newPathError("restore", p, err)
+		}
+	}
+	return nil
+}
+
+// isDirNotEmpty reports whether p is a directory with entries in it.
+func isDirNotEmpty(p string) bool {
+	entries, err := os.ReadDir(p)
+	return err == nil && len(entries) > 0
+}
+
+// restoreEntry recreates a single entry of a snapshot, unless it is already
+// in place.
+func (d *Directory) restoreEntry(ctx context.Context, store *SnapshotStore, entry SnapshotEntry) error {
+	p := d.Join(entry.Path)
+
+	switch entry.Type {
+	case "dir":
+		if err := os.MkdirAll(p, 0755); err != nil {
+			return newPathError("restore", p, err)
+		}
+		// the directory may exist read-only until its mode is reset
+		return newPathError("restore", p, os.Chmod(p, 0755))
+
+	case "symlink":
+		if target, err := os.Readlink(p); err == nil && target == entry.Target {
+			return nil
+		}
+		os.Remove(p)
+		return newPathError("restore", p, os.Symlink(entry.Target, p))
+
+	case "file":
+		if info, err := os.Lstat(p); err == nil && info.Size() == entry.Size {
+			if digest, err := NewFile(p).HashContext(ctx); err == nil && digest == entry.Digest {
+				return nil
+			}
+		}
+
+		r, err := store.blobs.Get(entry.Digest)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		return atomicWriteFile(p, entry.Mode.Perm(), func(w io.Writer) error {
+			_, err := copyContext(ctx, w, r)
+			return err
+		})
+	}
+
+	return &PathError{Op: "restore", Path: p, Err: errors.New("unknown entry type " + entry.Type)}
+}
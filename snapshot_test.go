@@ -0,0 +1,185 @@
+package filic_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		"config.json":      `{"v": 1}`,
+		"data/records.csv": "a,b\n1,2\n",
+		"data/keep.txt":    "keep",
+	})
+	os.Symlink("config.json", dir.Join("current"))
+	os.Chmod(dir.Join("data/keep.txt"), 0600)
+	past := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	os.Chtimes(dir.Join("config.json"), past, past)
+
+	store := filic.NewSnapshotStore(filic.NewDirectory(os.TempDir() + "/filic-snapshots"))
+	defer os.RemoveAll(store.Dir.Path)
+
+	snap, err := dir.Snapshot(store, filic.SnapshotOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a risky migration
+	filic.NewFile(dir.Join("config.json")).Write([]byte(`{"v": 2}`))
+	os.RemoveAll(dir.Join("data"))
+	os.WriteFile(dir.Join("data"), []byte("now a file"), 0644)
+	os.Remove(dir.Join("current"))
+	os.WriteFile(dir.Join("new.txt"), []byte("new"), 0644)
+
+	if err := dir.Restore(snap); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	dir.Walk(func(entity *filic.Entity) error {
+		names = append(names, entity.Path[len(dir.Path)+1:])
+		return nil
+	})
+	expectNames(t, []string{"config.json", "current", "data", "data/keep.txt", "data/records.csv"}, names)
+
+	content, _ := filic.NewFile(dir.Join("config.json")).ReadString()
+	if content != `{"v": 1}` {
+		t.Errorf("Expected restored content, got %q", content)
+	}
+
+	info, _ := os.Stat(dir.Join("config.json"))
+	if !info.ModTime().Equal(past) {
+		t.Errorf("Expected modification time %v, got %v", past, info.ModTime())
+	}
+
+	info, _ = os.Stat(dir.Join("data/keep.txt"))
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	target, err := os.Readlink(dir.Join("current"))
+	if err != nil || target != "config.json" {
+		t.Errorf("Expected symlink to config.json, got %q (%v)", target, err)
+	}
+
+	cleanup()
+}
+
+func TestSnapshotListDiffPrune(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		"a.txt": "a",
+		"b.txt": "b",
+	})
+
+	store := filic.NewSnapshotStore(filic.NewDirectory(os.TempDir() + "/filic-snapshots"))
+	defer os.RemoveAll(store.Dir.Path)
+
+	first, err := dir.Snapshot(store, filic.SnapshotOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filic.NewFile(dir.Join("b.txt")).Write([]byte("changed"))
+	os.Remove(dir.Join("a.txt"))
+	os.WriteFile(dir.Join("c.txt"), []byte("c"), 0644)
+
+	second, err := dir.Snapshot(store, filic.SnapshotOptions{Parent: first})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snaps, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != 2 || snaps[0].ID != first.ID || snaps[1].ID != second.ID {
+		t.Fatalf("Expected both snapshots oldest first, got %v", len(snaps))
+	}
+
+	var changes []string
+	for _, entry := range snaps[0].Diff(snaps[1]) {
+		changes = append(changes, entry.Kind.String()+" "+entry.Path)
+	}
+	expectNames(t, []string{"removed a.txt", "modified b.txt", "added c.txt"}, changes)
+
+	if err := store.Delete(first.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := store.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first manifest, "a" and "b" are no longer used
+	if len(report.Removed) != 3 {
+		t.Errorf("Expected 3 blobs pruned, got %v", len(report.Removed))
+	}
+
+	if _, err := store.Get(first.ID); !errors.Is(err, filic.ErrNotExist) {
+		t.Errorf("Expected ErrNotExist for a deleted snapshot, got %v", err)
+	}
+
+	restored := filic.NewDirectory(os.TempDir() + "/filic-restored")
+	defer os.RemoveAll(restored.Path)
+
+	if err := restored.Restore(second); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := filic.NewFile(restored.Join("b.txt")).ReadString()
+	if content != "changed" {
+		t.Errorf("Expected restored content %q, got %q", "changed", content)
+	}
+
+	cleanup()
+}
+
+func TestSnapshotRestoreKeepsIgnored(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		"main.go":                   "package main",
+		"node_modules/dep/index.js": "module.exports = 1",
+	})
+	store := filic.NewSnapshotStore(filic.NewDirectory(dir.Join(".snapshots")))
+
+	snap, err := dir.Snapshot(store, filic.SnapshotOptions{Ignore: filic.NewIgnoreMatcher("node_modules/")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range snap.Entries {
+		if entry.Path == ".snapshots" || entry.Path == "node_modules" {
+			t.Errorf("Expected %s to be left out of the snapshot", entry.Path)
+		}
+	}
+
+	// the ignore rules are read back with the snapshot
+	snap, err = store.Get(snap.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.WriteFile(dir.Join("extra.txt"), []byte("extra"), 0644)
+	if err := dir.Restore(snap); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(dir.Join("extra.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected extra.txt to be removed, got %v", err)
+	}
+	if _, err := os.Stat(dir.Join("node_modules/dep/index.js")); err != nil {
+		t.Errorf("Expected ignored files to be kept, got %v", err)
+	}
+	if snaps, err := store.List(); err != nil || len(snaps) != 1 {
+		t.Errorf("Expected the store to be kept, got %d snapshots (%v)", len(snaps), err)
+	}
+
+	cleanup()
+}
@@ -0,0 +1,11 @@
+//go:build darwin || freebsd
+
+package filic
+
+import "syscall"
+
+// statfsBlockSize returns the unit of the block counts of st, which the BSDs
+// report as Bsize.
+func statfsBlockSize(st *syscall.Statfs_t) uint64 {
+	return uint64(st.Bsize)
+}
@@ -0,0 +1,11 @@
+//go:build linux
+
+package filic
+
+import "syscall"
+
+// statfsBlockSize returns the unit of the block counts of st. On Linux that
+// is the fragment size; Bsize is the preferred I/O size, which can differ.
+func statfsBlockSize(st *syscall.Statfs_t) uint64 {
+	return uint64(st.Frsize)
+}
@@ -0,0 +1,12 @@
+//go:build !(linux || darwin || freebsd)
+
+package filic
+
+import "errors"
+
+// FSStats reports the capacity and usage of the file system containing the
+// directory. It is not supported on this platform and always returns an
+// error matching errors.ErrUnsupported.
+func (d *Directory) FSStats() (*FSStats, error) {
+	return nil, &PathError{Op: "statfs", Path: d.Path, Err: errors.ErrUnsupported}
+}
@@ -0,0 +1,23 @@
+//go:build linux || darwin || freebsd
+
+package filic
+
+import "syscall"
+
+// FSStats reports the capacity and usage of the file system containing the
+// directory, like statfs(2). Sizes are in bytes.
+func (d *Directory) FSStats() (*FSStats, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(d.Path, &st); err != nil {
+		return nil, newPathError("statfs", d.Path, err)
+	}
+
+	blockSize := statfsBlockSize(&st)
+	return &FSStats{
+		Total:      uint64(st.Blocks) * blockSize,
+		Free:       uint64(st.Bfree) * blockSize,
+		Available:  uint64(st.Bavail) * blockSize,
+		Inodes:     uint64(st.Files),
+		FreeInodes: uint64(st.Ffree),
+	}, nil
+}
@@ -0,0 +1,73 @@
+package filic
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// contextReader wraps an io.Reader and fails with the context's error once
+// the context is cancelled, so that long copies stop at the next read.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// copyContext copies from src to dst like io.Copy, checking ctx between reads.
+func copyContext(ctx context.Context, dst io.Writer, src io.Reader) (int64, error) {
+	return io.Copy(dst, &contextReader{ctx: ctx, r: src})
+}
+
+// ReadTo streams the contents of the file into w without loading the whole
+// file into memory. It returns the number of bytes written.
+func (f *File) ReadTo(w io.Writer) (int64, error) {
+	return f.ReadToContext(context.Background(), w)
+}
+
+// ReadToContext is like ReadTo but stops and returns the context's error
+// once ctx is cancelled.
+func (f *File) ReadToContext(ctx context.Context, w io.Writer) (int64, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return 0, newPathError("read", f.Path, err)
+	}
+	defer file.Close()
+
+	n, err := copyContext(ctx, w, file)
+	if err != nil {
+		return n, newPathError("read", f.Path, err)
+	}
+	return n, nil
+}
+
+// WriteFrom streams everything read from r into the file, replacing any
+// existing content. Like Write, the file is created with 0644 permissions
+// if it doesn't exist. It returns the number of bytes written.
+func (f *File) WriteFrom(r io.Reader) (int64, error) {
+	return f.WriteFromContext(context.Background(), r)
+}
+
+// WriteFromContext is like WriteFrom but stops and returns the context's
+// error once ctx is cancelled. The file is left with whatever was written
+// before the cancellation.
+func (f *File) WriteFromContext(ctx context.Context, r io.Reader) (int64, error) {
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, newPathError("write", f.Path, err)
+	}
+
+	n, err := copyContext(ctx, file, r)
+	if err != nil {
+		file.Close()
+		return n, newPathError("write", f.Path, err)
+	}
+
+	return n, newPathError("write", f.Path, file.Close())
+}
@@ -0,0 +1,278 @@
+package filic
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"time"
+)
+
+// SyncOptions configures Directory.SyncTo.
+type SyncOptions struct {
+	// Delete removes entries from the destination that don't exist in the
+	// source. Ignored entries are never deleted.
+	Delete bool
+	// PreserveMetadata copies permission bits and modification times from
+	// the source. Without it, comparing by CompareSizeModTime considers
+	// every previously synced file modified.
+	PreserveMetadata bool
+	// Compare selects how files present on both sides are compared.
+	Compare CompareMode
+	// DryRun only reports what would be done, without changing anything.
+	DryRun bool
+	// BytesPerSecond limits the rate at which file contents are copied.
+	// Zero means no limit.
+	BytesPerSecond int64
+	// Ignore, if set, excludes matching entries on both sides.
+	Ignore *IgnoreMatcher
+}
+
+// SyncReport describes what a sync did, or would do for a dry run. Paths
+// are relative to the synced directories and ordered by path.
+type SyncReport struct {
+	// Copied lists the files and symbolic links written to the destination.
+	Copied []string `json:"copied"`
+	// Created lists the directories created in the destination.
+	Created []string `json:"created"`
+	// Deleted lists the entries removed from the destination.
+	Deleted []string `json:"deleted"`
+	// Bytes is the number of bytes of file content copied.
+	Bytes int64 `json:"bytes"`
+}
+
+// SyncTo makes dest mirror the directory: new and changed files are copied,
+// and with opts.Delete entries that only exist in dest are removed. Changed
+// files are replaced atomically, so readers of dest never see a partially
+// written file.
+func (d *Directory) SyncTo(dest *Directory, opts SyncOptions) (*SyncReport, error) {
+	return d.SyncToContext(context.Background(), dest, opts)
+}
+
+// SyncToContext is like SyncTo but stops and returns the context's error
+// once ctx is cancelled, along with the report of what was done so far.
+func (d *Directory) SyncToContext(ctx context.Context, dest *Directory, opts SyncOptions) (*SyncReport, error) {
+	report := &SyncReport{}
+
+	if !opts.DryRun {
+		if err := dest.Create(); err != nil {
+			return report, err
+		}
+	}
+
+	// a dry run may target a destination that doesn't exist yet
+	destTree := map[string]fs.FileInfo{}
+	if dest.Exists() {
+		tree, err := scanTree(ctx, dest, opts.Ignore)
+		if err != nil {
+			return report, err
+		}
+		destTree = tree
+	}
+
+	srcTree, err := scanTree(ctx, d, opts.Ignore)
+	if err != nil {
+		return report, err
+	}
+
+	entries, err := compareTrees(ctx, dest.Path, d.Path, destTree, srcTree, opts.Compare)
+	if err != nil {
+		return report, err
+	}
+
+	s := &syncer{src: d.Path, dest: dest.Path, opts: opts, report: report, deleted: map[string]bool{}}
+
+	var createdDirs []string
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		switch entry.Kind {
+		case DiffRemoved:
+			// entries below a deleted directory went with it
+			if opts.Delete && !s.isDeleted(path.Dir(entry.Path)) {
+				err = s.delete(entry.Path)
+			}
+
+		case DiffTypeChanged:
+			if err = s.delete(entry.Path); err == nil {
+				err = s.add(ctx, entry)
+			}
+
+		default:
+			err = s.add(ctx, entry)
+		}
+		if err != nil {
+			return report, err
+		}
+
+		if entry.NewType == "dir" && entry.Kind != DiffRemoved {
+			createdDirs = append(createdDirs, entry.Path)
+		}
+	}
+
+	// directory times change while their contents are written, so they are
+	// set last, deepest first
+	if opts.PreserveMetadata && !opts.DryRun {
+		for i := len(createdDirs) - 1; i >= 0; i-- {
+			if err := s.preserveTimes(createdDirs[i]); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// syncer applies the entries of a diff to the destination of a sync.
+type syncer struct {
+	src, dest string
+	opts      SyncOptions
+	report    *SyncReport
+	deleted   map[string]bool
+}
+
+// delete removes rel from the destination.
+func (s *syncer) delete(rel string) error {
+	s.report.Deleted = append(s.report.Deleted, rel)
+	s.deleted[rel] = true
+	if s.opts.DryRun {
+		return nil
+	}
+	return NewEntity(path.Join(s.dest, rel)).Delete()
+}
+
+// isDeleted reports whether rel or one of its parents was deleted from the
+// destination during the sync.
+func (s *syncer) isDeleted(rel string) bool {
+	for ; rel != "." && rel != "/"; rel = path.Dir(rel) {
+		if s.deleted[rel] {
+			return true
+		}
+	}
+	return false
+}
+
+// add copies an entry that is new or changed in the source.
+func (s *syncer) add(ctx context.Context, entry DiffEntry) error {
+	src, target := path.Join(s.src, entry.Path), path.Join(s.dest, entry.Path)
+
+	switch entry.NewType {
+	case "dir":
+		s.report.Created = append(s.report.Created, entry.Path)
+		if s.opts.DryRun {
+			return nil
+		}
+
+		info, err := os.Stat(src)
+		if err != nil {
+			return newPathError("sync", src, err)
+		}
+		return newPathError("sync", target, os.MkdirAll(target, info.Mode().Perm()))
+
+	case "symlink":
+		s.report.Copied = append(s.report.Copied, entry.Path)
+		if s.opts.DryRun {
+			return nil
+		}
+
+		link, err := os.Readlink(src)
+		if err != nil {
+			return newPathError("sync", src, err)
+		}
+		os.Remove(target)
+		return newPathError("sync", target, os.Symlink(link, target))
+
+	case "file":
+		s.report.Copied = append(s.report.Copied, entry.Path)
+		s.report.Bytes += entry.NewSize
+		if s.opts.DryRun {
+			return nil
+		}
+		return s.copyFile(ctx, src, target)
+	}
+
+	// sockets, devices and named pipes are not synced
+	return nil
+}
+
+// copyFile atomically replaces target with the content of src.
+func (s *syncer) copyFile(ctx context.Context, src, target string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return newPathError("sync", src, err)
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return newPathError("sync", src, err)
+	}
+
+	perm := info.Mode().Perm()
+	if !s.opts.PreserveMetadata {
+		if existing, err := os.Stat(target); err == nil {
+			perm = existing.Mode().Perm()
+		}
+	}
+
+	err = atomicWriteFile(target, perm, func(w io.Writer) error {
+		if s.opts.BytesPerSecond > 0 {
+			w = &throttledWriter{ctx: ctx, w: w, rate: s.opts.BytesPerSecond, start: time.Now()}
+		}
+		_, err := copyContext(ctx, w, in)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.opts.PreserveMetadata {
+		return newPathError("sync", target, os.Chtimes(target, time.Time{}, info.ModTime()))
+	}
+	return nil
+}
+
+// preserveTimes copies the modification time of the source directory rel.
+func (s *syncer) preserveTimes(rel string) error {
+	info, err := os.Stat(path.Join(s.src, rel))
+	if err != nil {
+		return newPathError("sync", path.Join(s.src, rel), err)
+	}
+	target := path.Join(s.dest, rel)
+	return newPathError("sync", target, os.Chtimes(target, time.Time{}, info.ModTime()))
+}
+
+// throttledWriter limits the average rate of writes to rate bytes per
+// second by sleeping after writes that get ahead of it.
+type throttledWriter struct {
+	ctx     context.Context
+	w       io.Writer
+	rate    int64
+	start   time.Time
+	written int64
+}
+
+func (t *throttledWriter) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	t.written += int64(n)
+	if err != nil {
+		return n, err
+	}
+
+	due := t.start.Add(time.Duration(float64(t.written) / float64(t.rate) * float64(time.Second)))
+	if wait := time.Until(due); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-t.ctx.Done():
+			return n, t.ctx.Err()
+		}
+	}
+
+	return n, nil
+}
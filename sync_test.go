@@ -0,0 +1,174 @@
+package filic_test
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func setupSyncTrees(t *testing.T) (*filic.Directory, *filic.Directory) {
+	t.Helper()
+
+	createTree(t, map[string]string{
+		"src/a.txt":        "new a",
+		"src/same.txt":     "same",
+		"src/sub/b.txt":    "b",
+		"dest/a.txt":       "old a",
+		"dest/extra.txt":   "extra",
+		"dest/ignored.log": "keep me",
+	})
+
+	src := filic.NewDirectory(path.Join(getTempDirPath(), "src"))
+	dest := filic.NewDirectory(path.Join(getTempDirPath(), "dest"))
+
+	return src, dest
+}
+
+func TestSyncToDryRun(t *testing.T) {
+	cleanup()
+
+	src, dest := setupSyncTrees(t)
+
+	report, err := src.SyncTo(dest, filic.SyncOptions{Delete: true, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectNames(t, []string{"a.txt", "same.txt", "sub/b.txt"}, report.Copied)
+	expectNames(t, []string{"sub"}, report.Created)
+	expectNames(t, []string{"extra.txt", "ignored.log"}, report.Deleted)
+
+	if filic.NewDirectory(dest.Join("sub")).Exists() {
+		t.Error("Dry run should not create directories")
+	}
+
+	content, _ := filic.NewFile(dest.Join("a.txt")).ReadString()
+	if content != "old a" {
+		t.Errorf("Dry run should not change files, got %q", content)
+	}
+
+	// a dry run to a missing destination reports everything
+	report, err = src.SyncTo(filic.NewDirectory(path.Join(getTempDirPath(), "missing")), filic.SyncOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNames(t, []string{"a.txt", "same.txt", "sub/b.txt"}, report.Copied)
+
+	cleanup()
+}
+
+func TestSyncTo(t *testing.T) {
+	cleanup()
+
+	src, dest := setupSyncTrees(t)
+
+	stamp := time.Now().Add(-time.Hour).Truncate(time.Second)
+	os.Chtimes(src.Join("a.txt"), stamp, stamp)
+	os.Chmod(src.Join("a.txt"), 0600)
+
+	opts := filic.SyncOptions{
+		Delete:           true,
+		PreserveMetadata: true,
+		Ignore:           filic.NewIgnoreMatcher("*.log"),
+	}
+
+	report, err := src.SyncTo(dest, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectNames(t, []string{"extra.txt"}, report.Deleted)
+
+	names, err := dest.Find().Files().Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNames(t, []string{"a.txt", "ignored.log", "same.txt", "sub/b.txt"}, relNames(dest, names))
+
+	info, err := os.Stat(dest.Join("a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(stamp) {
+		t.Errorf("Expected modification time %v, got %v", stamp, info.ModTime())
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	content, _ := filic.NewFile(dest.Join("a.txt")).ReadString()
+	if content != "new a" {
+		t.Errorf("Expected %q, got %q", "new a", content)
+	}
+
+	// with metadata preserved, a second sync has nothing to do
+	report, err = src.SyncTo(dest, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Copied)+len(report.Created)+len(report.Deleted) != 0 {
+		t.Errorf("Expected nothing to sync, got %+v", report)
+	}
+
+	cleanup()
+}
+
+func TestSyncToThrottled(t *testing.T) {
+	cleanup()
+
+	createTree(t, map[string]string{"src/big.bin": string(make([]byte, 4096))})
+
+	src := filic.NewDirectory(path.Join(getTempDirPath(), "src"))
+	dest := filic.NewDirectory(path.Join(getTempDirPath(), "dest"))
+
+	start := time.Now()
+	_, err := src.SyncTo(dest, filic.SyncOptions{BytesPerSecond: 16384})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Expected throttled copy to take at least 200ms, took %v", elapsed)
+	}
+
+	cleanup()
+}
+
+func TestSyncToDirectoryReplacedByFile(t *testing.T) {
+	cleanup()
+
+	createTree(t, map[string]string{
+		"src/a":           "now a file",
+		"dest/a/x/y.txt":  "y",
+		"dest/a/z.txt":    "z",
+		"dest/a-b/c.txt":  "c",
+		"dest/gone/d.txt": "d",
+	})
+
+	src := filic.NewDirectory(path.Join(getTempDirPath(), "src"))
+	dest := filic.NewDirectory(path.Join(getTempDirPath(), "dest"))
+
+	for _, dryRun := range []bool{true, false} {
+		report, err := src.SyncTo(dest, filic.SyncOptions{Delete: true, DryRun: dryRun})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectNames(t, []string{"a", "a-b", "gone"}, report.Deleted)
+	}
+
+	names, err := dest.Find().Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNames(t, []string{"a"}, relNames(dest, names))
+
+	content, _ := filic.NewFile(dest.Join("a")).ReadString()
+	if content != "now a file" {
+		t.Errorf("Expected %q, got %q", "now a file", content)
+	}
+
+	cleanup()
+}
@@ -0,0 +1,33 @@
+//go:build !unix
+
+package filic
+
+import "io/fs"
+
+// sysErrorSentinel reports that system errors are not mapped to filic
+// sentinels on this platform.
+func sysErrorSentinel(err error) error {
+	return nil
+}
+
+// fileOwner reports that file ownership is not available on this platform.
+func fileOwner(info fs.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
+
+// fileIdentity reports that inode numbers are not available on this platform.
+func fileIdentity(info fs.FileInfo) (dev, ino, nlink uint64, ok bool) {
+	return 0, 0, 0, false
+}
+
+// allocatedSize approximates the allocated size with the apparent size on
+// platforms that don't report allocated blocks.
+func allocatedSize(info fs.FileInfo) int64 {
+	return info.Size()
+}
+
+// isCrossDevice reports that cross-device renames are not detected on this
+// platform.
+func isCrossDevice(err error) bool {
+	return false
+}
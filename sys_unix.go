@@ -0,0 +1,56 @@
+//go:build unix
+
+package filic
+
+import (
+	"errors"
+	"io/fs"
+	"syscall"
+)
+
+// sysErrorSentinel returns the filic sentinel matching a system error, or
+// nil if there is none.
+func sysErrorSentinel(err error) error {
+	switch {
+	case errors.Is(err, syscall.ENOTDIR):
+		return ErrNotDirectory
+	case errors.Is(err, syscall.EISDIR):
+		return ErrIsDirectory
+	}
+	return nil
+}
+
+// fileOwner returns the user and group IDs owning the file described by info.
+func fileOwner(info fs.FileInfo) (uid, gid int, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(stat.Uid), int(stat.Gid), true
+}
+
+// fileIdentity returns the device and inode numbers identifying the file
+// described by info, and its number of hard links.
+func fileIdentity(info fs.FileInfo) (dev, ino, nlink uint64, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, 0, false
+	}
+	return uint64(stat.Dev), uint64(stat.Ino), uint64(stat.Nlink), true
+}
+
+// allocatedSize returns the number of bytes of storage allocated to the file
+// described by info.
+func allocatedSize(info fs.FileInfo) int64 {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.Size()
+	}
+	return int64(stat.Blocks) * 512
+}
+
+// isCrossDevice reports whether err is a rename failing because its source
+// and destination are on different file systems.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
@@ -0,0 +1,83 @@
+package filic
+
+import (
+	"os"
+	"time"
+)
+
+// Times holds the timestamps of an entity with nanosecond precision, as far
+// as the file system records them.
+type Times struct {
+	// Access is the last access time. On platforms that don't report it,
+	// it is the modification time.
+	Access time.Time
+	// Modify is the last modification time.
+	Modify time.Time
+	// Change is the last status change time, or the zero time on platforms
+	// that don't report it.
+	Change time.Time
+}
+
+// Times returns the timestamps of the entity, following symbolic links.
+func (e *Entity) Times() (Times, error) {
+	info, err := os.Stat(e.Path)
+	if err != nil {
+		return Times{}, newPathError("stat", e.Path, err)
+	}
+
+	times := Times{Access: info.ModTime(), Modify: info.ModTime()}
+	if atime, ctime, ok := fileTimes(info); ok {
+		times.Access, times.Change = atime, ctime
+	}
+	return times, nil
+}
+
+// ModTime returns the modification time of the entity, following symbolic
+// links.
+func (e *Entity) ModTime() (time.Time, error) {
+	info, err := os.Stat(e.Path)
+	if err != nil {
+		return time.Time{}, newPathError("stat", e.Path, err)
+	}
+	return info.ModTime(), nil
+}
+
+// SetTimes sets the access and modification times of the entity. A zero
+// time leaves the corresponding timestamp unchanged.
+func (e *Entity) SetTimes(atime, mtime time.Time) error {
+	return newPathError("chtimes", e.Path, os.Chtimes(e.Path, atime, mtime))
+}
+
+// Touch works like the touch command: it sets the access and modification
+// times of the entity to the current time, creating an empty file with 0644
+// permissions (rw-r--r--) if nothing exists at the path. Parent directories
+// are not created.
+func (e *Entity) Touch() error {
+	if !e.Exists() {
+		file, err := os.OpenFile(e.Path, os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return newPathError("touch", e.Path, err)
+		}
+		file.Close()
+	}
+
+	now := time.Now()
+	return newPathError("touch", e.Path, os.Chtimes(e.Path, now, now))
+}
+
+// WriteOptions configures File.WriteWithOptions.
+type WriteOptions struct {
+	// PreserveModTime leaves the file untouched, keeping its modification
+	// time, when it already holds exactly the data being written. Build
+	// tools relying on modification times then don't see a change.
+	PreserveModTime bool
+}
+
+// WriteWithOptions is like Write but configured by opts.
+func (f *File) WriteWithOptions(data []byte, opts WriteOptions) error {
+	if opts.PreserveModTime {
+		_, err := f.WriteIfChanged(data)
+		return err
+	}
+	return f.Write(data)
+}
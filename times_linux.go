@@ -0,0 +1,19 @@
+//go:build linux
+
+package filic
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// fileTimes returns the access and status change times of the file
+// described by info.
+func fileTimes(info fs.FileInfo) (atime, ctime time.Time, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	return time.Unix(stat.Atim.Unix()), time.Unix(stat.Ctim.Unix()), true
+}
@@ -0,0 +1,14 @@
+//go:build !(linux || darwin || freebsd || netbsd)
+
+package filic
+
+import (
+	"io/fs"
+	"time"
+)
+
+// fileTimes reports that access and change times are not available on this
+// platform.
+func fileTimes(info fs.FileInfo) (atime, ctime time.Time, ok bool) {
+	return time.Time{}, time.Time{}, false
+}
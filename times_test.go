@@ -0,0 +1,113 @@
+package filic_test
+
+import (
+	"path"
+	"testing"
+	"time"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func TestTouch(t *testing.T) {
+	cleanup()
+
+	filic.NewDirectory(getTempDirPath()).Create()
+	entity := filic.NewEntity(path.Join(getTempDirPath(), "touched"))
+
+	if err := entity.Touch(); err != nil {
+		t.Fatal(err)
+	}
+	if !entity.Exists() {
+		t.Error("Touch should create the file")
+	}
+
+	old := time.Now().Add(-time.Hour)
+	if err := entity.SetTimes(old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := entity.Touch(); err != nil {
+		t.Fatal(err)
+	}
+
+	modTime, err := entity.ModTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(modTime) > time.Minute {
+		t.Errorf("Touch should update the modification time, got %v", modTime)
+	}
+
+	// touching a directory updates its times too
+	dir := filic.NewEntity(getTempDirPath())
+	if err := dir.Touch(); err != nil {
+		t.Error(err)
+	}
+
+	cleanup()
+}
+
+func TestSetTimesNanoseconds(t *testing.T) {
+	cleanup()
+
+	file := filic.NewFile(path.Join(getTempDirPath(), "times.txt"))
+	file.Create()
+
+	atime := time.Date(2020, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	mtime := time.Date(2021, 6, 7, 8, 9, 10, 987654321, time.UTC)
+
+	if err := file.SetTimes(atime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	times, err := file.Times()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !times.Modify.Equal(mtime) {
+		t.Errorf("Expected modification time %v, got %v", mtime, times.Modify)
+	}
+	if !times.Access.Equal(atime) {
+		t.Errorf("Expected access time %v, got %v", atime, times.Access)
+	}
+
+	cleanup()
+}
+
+func TestWritePreserveModTime(t *testing.T) {
+	cleanup()
+
+	file := filic.NewFile(path.Join(getTempDirPath(), "stable.txt"))
+	file.Create()
+	file.Write([]byte("content"))
+
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	file.SetTimes(old, old)
+
+	opts := filic.WriteOptions{PreserveModTime: true}
+	if err := file.WriteWithOptions([]byte("content"), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	modTime, _ := file.ModTime()
+	if !modTime.Equal(old) {
+		t.Errorf("Expected modification time to stay %v, got %v", old, modTime)
+	}
+
+	if err := file.WriteWithOptions([]byte("changed"), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	modTime, _ = file.ModTime()
+	if modTime.Equal(old) {
+		t.Error("Expected modification time to change with the content")
+	}
+
+	content, _ := file.ReadString()
+	if content != "changed" {
+		t.Errorf("Expected %q, got %q", "changed", content)
+	}
+
+	cleanup()
+}
@@ -0,0 +1,302 @@
+package filic
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// trashInfoTimeFormat is the format of DeletionDate in .trashinfo files.
+const trashInfoTimeFormat = "2006-01-02T15:04:05"
+
+// Trash is a trash can following the freedesktop.org Trash specification,
+// as used by desktop environments on Linux and other Unix systems. Trashed
+// entities are kept in its "files" directory, next to a ".trashinfo" file
+// in its "info" directory recording where they came from.
+type Trash struct {
+	Dir *Directory
+}
+
+// TrashItem is an entity stored in a Trash.
+type TrashItem struct {
+	// Name identifies the item within its trash.
+	Name string
+	// OriginalPath is the absolute path the item was trashed from.
+	OriginalPath string
+	// DeletionDate is when the item was trashed, with second precision.
+	DeletionDate time.Time
+
+	trash *Trash
+}
+
+// NewTrash returns the trash rooted at dir. The directory and its "files"
+// and "info" subdirectories are created when the first entity is trashed.
+func NewTrash(dir *Directory) *Trash {
+	return &Trash{Dir: dir}
+}
+
+// HomeTrash returns the home trash of the current user, which is
+// $XDG_DATA_HOME/Trash, defaulting to ~/.local/share/Trash.
+func HomeTrash() (*Trash, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		dataHome = path.Join(home, ".local", "share")
+	}
+	return NewTrash(NewDirectory(path.Join(dataHome, "Trash"))), nil
+}
+
+func (t *Trash) filesDir() string { return t.Dir.Join("files") }
+func (t *Trash) infoDir() string  { return t.Dir.Join("info") }
+
+// infoPath returns the path of the .trashinfo file of the item called name.
+func (t *Trash) infoPath(name string) string {
+	return path.Join(t.infoDir(), name+".trashinfo")
+}
+
+// MoveToTrash moves the entity to the home trash of the current user instead
+// of deleting it permanently, so it can be restored later. The home trash
+// is always used, even for entities on other file systems, which are
+// copied into it and then deleted: the per-volume $topdir/.Trash-$uid
+// directories of the specification are not supported.
+func (e *Entity) MoveToTrash() (*TrashItem, error) {
+	trash, err := HomeTrash()
+	if err != nil {
+		return nil, newPathError("trash", e.Path, err)
+	}
+	return trash.Put(e)
+}
+
+// Put moves the entity into the trash. If the trash is on another file
+// system, the entity is copied into it and then deleted.
+func (t *Trash) Put(e *Entity) (*TrashItem, error) {
+	original, err := filepath.Abs(e.Path)
+	if err != nil {
+		return nil, newPathError("trash", e.Path, err)
+	}
+
+	info, err := os.Lstat(original)
+	if err != nil {
+		return nil, newPathError("trash", e.Path, err)
+	}
+
+	for _, dir := range []string{t.filesDir(), t.infoDir()} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, newPathError("trash", dir, err)
+		}
+	}
+
+	item := &TrashItem{OriginalPath: original, DeletionDate: time.Now().Truncate(time.Second), trash: t}
+
+	// the info file is created exclusively first, reserving the name
+	infoFile, err := t.reserveName(item, path.Base(original))
+	if err != nil {
+		return nil, newPathError("trash", e.Path, err)
+	}
+
+	content := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: original}).EscapedPath(), item.DeletionDate.Format(trashInfoTimeFormat))
+	_, err = infoFile.WriteString(content)
+	if closeErr := infoFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = moveEntity(original, path.Join(t.filesDir(), item.Name), info)
+	}
+
+	if err != nil {
+		os.Remove(t.infoPath(item.Name))
+		return nil, newPathError("trash", e.Path, err)
+	}
+
+	return item, nil
+}
+
+// reserveName picks a name for item that isn't used in the trash yet and
+// creates its info file.
+func (t *Trash) reserveName(item *TrashItem, base string) (*os.File, error) {
+	ext := path.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+
+	for i := 1; ; i++ {
+		name := base
+		if i > 1 {
+			name = fmt.Sprintf("%s.%d%s", stem, i, ext)
+		}
+
+		if _, err := os.Lstat(path.Join(t.filesDir(), name)); err == nil {
+			continue
+		}
+
+		file, err := os.OpenFile(t.infoPath(name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		item.Name = name
+		return file, nil
+	}
+}
+
+// moveEntity renames from to to, falling back to copying and deleting when
+// they are on different file systems.
+func moveEntity(from, to string, info os.FileInfo) error {
+	err := os.Rename(from, to)
+	if !isCrossDevice(err) {
+		return err
+	}
+
+	entity := &Entity{Path: from}
+	if info.IsDir() {
+		err = NewDirectory(from).CopyTo(NewDirectory(to))
+	} else {
+		err = copyEntity(context.Background(), entity, to)
+	}
+	if err != nil {
+		os.RemoveAll(to)
+		return err
+	}
+	return entity.Delete()
+}
+
+// List returns the items in the trash, most recently deleted first.
+// Entries without a matching, readable info file are skipped.
+func (t *Trash) List() ([]*TrashItem, error) {
+	names, err := NewDirectory(t.infoDir()).List()
+	if errors.Is(err, ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*TrashItem
+	for _, infoName := range names {
+		name, ok := strings.CutSuffix(infoName, ".trashinfo")
+		if !ok {
+			continue
+		}
+
+		item, err := t.readInfo(name)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Lstat(path.Join(t.filesDir(), name)); err != nil {
+			continue
+		}
+		items = append(items, item)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DeletionDate.After(items[j].DeletionDate)
+	})
+	return items, nil
+}
+
+// readInfo parses the .trashinfo file of the item called name.
+func (t *Trash) readInfo(name string) (*TrashItem, error) {
+	file, err := os.Open(t.infoPath(name))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	item := &TrashItem{Name: name, trash: t}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "Path":
+			original, err := url.PathUnescape(value)
+			if err != nil {
+				return nil, err
+			}
+			item.OriginalPath = original
+		case "DeletionDate":
+			date, err := time.ParseInLocation(trashInfoTimeFormat, value, time.Local)
+			if err != nil {
+				return nil, err
+			}
+			item.DeletionDate = date
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if item.OriginalPath == "" {
+		return nil, fmt.Errorf("trash info %v has no Path", name)
+	}
+	// relative paths are relative to the parent of the trash directory
+	if !path.IsAbs(item.OriginalPath) {
+		item.OriginalPath = path.Join(path.Dir(t.Dir.Path), item.OriginalPath)
+	}
+	return item, nil
+}
+
+// Entity returns the trashed entity as it is stored in the trash.
+func (i *TrashItem) Entity() *Entity {
+	return NewEntity(path.Join(i.trash.filesDir(), i.Name))
+}
+
+// Restore moves the item back to its original path, creating missing parent
+// directories. It fails with an error matching ErrExist if something
+// already exists there.
+func (i *TrashItem) Restore() error {
+	stored := i.Entity().Path
+
+	if _, err := os.Lstat(i.OriginalPath); err == nil {
+		return &PathError{Op: "restore", Path: i.OriginalPath, Err: ErrExist}
+	}
+
+	info, err := os.Lstat(stored)
+	if err != nil {
+		return newPathError("restore", stored, err)
+	}
+
+	if err := os.MkdirAll(path.Dir(i.OriginalPath), 0755); err != nil {
+		return newPathError("restore", i.OriginalPath, err)
+	}
+
+	if err := moveEntity(stored, i.OriginalPath, info); err != nil {
+		return newPathError("restore", i.OriginalPath, err)
+	}
+
+	return newPathError("restore", stored, os.Remove(i.trash.infoPath(i.Name)))
+}
+
+// Delete permanently deletes the item from the trash.
+func (i *TrashItem) Delete() error {
+	if err := i.Entity().Delete(); err != nil {
+		return err
+	}
+	return newPathError("delete", i.trash.infoPath(i.Name), os.Remove(i.trash.infoPath(i.Name)))
+}
+
+// Empty permanently deletes everything in the trash.
+func (t *Trash) Empty() error {
+	for _, dir := range []string{t.filesDir(), t.infoDir(), t.Dir.Join("directorysizes")} {
+		if err := NewEntity(dir).Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
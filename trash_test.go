@@ -0,0 +1,118 @@
+package filic_test
+
+import (
+	"errors"
+	"path"
+	"testing"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func TestMoveToTrashAndRestore(t *testing.T) {
+	cleanup()
+
+	t.Setenv("XDG_DATA_HOME", path.Join(getTempDirPath(), "data"))
+
+	dir := createTree(t, map[string]string{
+		"work/report.txt": "important",
+		"work/sub/x.txt":  "x",
+	})
+
+	file := filic.NewFile(dir.Join("work/report.txt"))
+
+	item, err := file.MoveToTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if file.Exists() {
+		t.Error("File should have been moved to the trash")
+	}
+	if item.OriginalPath != file.Path {
+		t.Errorf("Expected original path %v, got %v", file.Path, item.OriginalPath)
+	}
+
+	info, err := filic.NewFile(path.Join(getTempDirPath(), "data/Trash/info/report.txt.trashinfo")).ReadString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info[:13] != "[Trash Info]\n" {
+		t.Errorf("Unexpected trash info %q", info)
+	}
+
+	// trashing another entity with the same name doesn't clash
+	filic.NewFile(file.Path).Write([]byte("second"))
+	second, err := file.MoveToTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Name == item.Name {
+		t.Errorf("Expected a unique name, got %v twice", item.Name)
+	}
+
+	trash, err := filic.HomeTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := trash.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 trashed items, got %d", len(items))
+	}
+
+	if err := item.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := file.ReadString()
+	if content != "important" {
+		t.Errorf("Expected restored content %q, got %q", "important", content)
+	}
+
+	// the second item can't be restored over the first
+	if err := second.Restore(); !errors.Is(err, filic.ErrExist) {
+		t.Errorf("Expected ErrExist, got %v", err)
+	}
+
+	cleanup()
+}
+
+func TestTrashDirectoryAndEmpty(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{"work/sub/x.txt": "x"})
+	trash := filic.NewTrash(filic.NewDirectory(path.Join(getTempDirPath(), "trash")))
+
+	sub := filic.NewDirectory(dir.Join("work/sub"))
+	if _, err := trash.Put(&sub.Entity); err != nil {
+		t.Fatal(err)
+	}
+	if sub.Exists() {
+		t.Error("Directory should have been moved to the trash")
+	}
+
+	items, _ := trash.List()
+	if len(items) != 1 {
+		t.Fatalf("Expected 1 trashed item, got %d", len(items))
+	}
+
+	if !filic.NewFile(path.Join(items[0].Entity().Path, "x.txt")).Exists() {
+		t.Error("Trashed directory should keep its contents")
+	}
+
+	if err := trash.Empty(); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := trash.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 0 {
+		t.Errorf("Expected empty trash, got %d items", len(items))
+	}
+
+	cleanup()
+}
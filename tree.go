@@ -0,0 +1,220 @@
+package filic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TreeOptions configures Tree and TreeNode.
+type TreeOptions struct {
+	// Depth limits how many levels below the directory are included. Zero
+	// includes the whole tree.
+	Depth int
+	// Hidden includes entries whose name starts with a dot.
+	Hidden bool
+	// Ignore, if set, leaves matching entries out of the tree.
+	Ignore *IgnoreMatcher
+	// Sort orders the entries of each directory. Entries are sorted by
+	// name when it is SortNone.
+	Sort SortBy
+	// Reverse reverses the order of the entries of each directory.
+	Reverse bool
+
+	// Size, Mode and ModTime add the size, permissions and modification
+	// time of every entry to the rendered tree.
+	Size    bool
+	Mode    bool
+	ModTime bool
+	// ASCII draws the tree with ASCII characters instead of Unicode box
+	// drawing characters.
+	ASCII bool
+}
+
+// TreeNode is an entry of a tree built by Directory.TreeNode. Symbolic links
+// are not followed and record their target.
+type TreeNode struct {
+	Name     string      `json:"name"`
+	Type     string      `json:"type"`
+	Mode     fs.FileMode `json:"mode"`
+	Size     int64       `json:"size"`
+	ModTime  time.Time   `json:"modTime"`
+	Target   string      `json:"target,omitempty"`
+	Children []*TreeNode `json:"children,omitempty"`
+}
+
+// treeTimeFormat is the format of modification times in rendered trees.
+const treeTimeFormat = "Jan _2 15:04"
+
+// Tree renders the tree below d like the `tree` command, followed by a
+// summary line counting directories and files.
+func (d *Directory) Tree(opts TreeOptions) (string, error) {
+	root, err := d.TreeNode(opts)
+	if err != nil {
+		return "", err
+	}
+	return root.Render(opts), nil
+}
+
+// TreeNode returns the tree below d as a structure, for rendering or for
+// encoding as JSON. The root node is named after the directory's path.
+func (d *Directory) TreeNode(opts TreeOptions) (*TreeNode, error) {
+	info, err := os.Stat(d.Path)
+	if err != nil {
+		return nil, newPathError("tree", d.Path, err)
+	}
+	if !info.IsDir() {
+		return nil, &PathError{Op: "tree", Path: d.Path, Err: ErrNotDirectory}
+	}
+
+	root := &TreeNode{Name: d.Path, Type: "dir", Mode: info.Mode(), Size: info.Size(), ModTime: info.ModTime()}
+	nodes := map[string]*TreeNode{".": root}
+
+	err = d.WalkWithOptions(context.Background(), WalkOptions{Ignore: opts.Ignore}, func(entity *Entity) error {
+		info, err := entity.lstat()
+		if err != nil {
+			return newPathError("tree", entity.Path, err)
+		}
+
+		name := entity.Name()
+		if !opts.Hidden && isHidden(name) {
+			return SkipDir
+		}
+
+		node := &TreeNode{
+			Name:    name,
+			Type:    entityType(info),
+			Mode:    info.Mode(),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		}
+		if node.Type == "symlink" {
+			node.Target, _ = os.Readlink(entity.Path)
+		}
+
+		rel := relativePath(d.Path, entity.Path)
+		parent := nodes[path.Dir(rel)]
+		parent.Children = append(parent.Children, node)
+
+		if info.IsDir() {
+			if opts.Depth > 0 && strings.Count(rel, "/")+1 >= opts.Depth {
+				return SkipDir
+			}
+			nodes[rel] = node
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	root.sortChildren(opts.Sort, opts.Reverse)
+	return root, nil
+}
+
+// sortChildren orders the children of the node and its descendants.
+func (n *TreeNode) sortChildren(by SortBy, reverse bool) {
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		a, b := n.Children[i], n.Children[j]
+		if reverse {
+			a, b = b, a
+		}
+		switch by {
+		case SortBySize:
+			if a.Size != b.Size {
+				return a.Size < b.Size
+			}
+		case SortByModTime:
+			if !a.ModTime.Equal(b.ModTime) {
+				return a.ModTime.Before(b.ModTime)
+			}
+		}
+		return a.Name < b.Name
+	})
+
+	for _, child := range n.Children {
+		child.sortChildren(by, reverse)
+	}
+}
+
+// Render draws the tree rooted at n like the `tree` command. Only the
+// display options of opts are used.
+func (n *TreeNode) Render(opts TreeOptions) string {
+	var out strings.Builder
+	out.WriteString(n.Name)
+	out.WriteByte('\n')
+
+	var dirs, files int
+	n.render(&out, "", opts, &dirs, &files)
+
+	fmt.Fprintf(&out, "\n%s, %s\n", plural(dirs, "directory", "directories"), plural(files, "file", "files"))
+	return out.String()
+}
+
+func (n *TreeNode) render(out *strings.Builder, prefix string, opts TreeOptions, dirs, files *int) {
+	branch, last, pipe := "├── ", "└── ", "│   "
+	if opts.ASCII {
+		branch, last, pipe = "|-- ", "`-- ", "|   "
+	}
+
+	for i, child := range n.Children {
+		connector, indent := branch, pipe
+		if i == len(n.Children)-1 {
+			connector, indent = last, "    "
+		}
+
+		out.WriteString(prefix)
+		out.WriteString(connector)
+		out.WriteString(child.label(opts))
+		out.WriteByte('\n')
+
+		if child.Type == "dir" {
+			*dirs++
+			child.render(out, prefix+indent, opts, dirs, files)
+		} else {
+			*files++
+		}
+	}
+}
+
+// label returns the line describing the node in a rendered tree.
+func (n *TreeNode) label(opts TreeOptions) string {
+	var fields []string
+	if opts.Mode {
+		fields = append(fields, n.Mode.String())
+	}
+	if opts.Size {
+		fields = append(fields, fmt.Sprintf("%10d", n.Size))
+	}
+	if opts.ModTime {
+		fields = append(fields, n.ModTime.Format(treeTimeFormat))
+	}
+
+	label := n.Name
+	if len(fields) > 0 {
+		label = "[" + strings.Join(fields, " ") + "]  " + label
+	}
+	if n.Type == "symlink" {
+		label += " -> " + n.Target
+	}
+	return label
+}
+
+// plural formats a count followed by the singular or plural noun.
+func plural(n int, singular, many string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
+
+// JSON returns the tree encoded as indented JSON.
+func (n *TreeNode) JSON() ([]byte, error) {
+	return json.MarshalIndent(n, "", "  ")
+}
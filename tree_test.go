@@ -0,0 +1,91 @@
+package filic_test
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func TestTree(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		"README.md":       "readme",
+		"src/main.go":     "package main",
+		"src/lib/util.go": "package lib",
+		".git/HEAD":       "ref",
+	})
+	os.Symlink("README.md", dir.Join("link"))
+
+	out, err := dir.Tree(filic.TreeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := dir.Path + `
+├── README.md
+├── link -> README.md
+└── src
+    ├── lib
+    │   └── util.go
+    └── main.go
+
+2 directories, 4 files
+`
+	if out != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, out)
+	}
+
+	out, _ = dir.Tree(filic.TreeOptions{Depth: 1, Hidden: true, ASCII: true, Reverse: true})
+	expected = dir.Path + "\n" +
+		"|-- src\n" +
+		"|-- link -> README.md\n" +
+		"|-- README.md\n" +
+		"`-- .git\n" +
+		"\n2 directories, 2 files\n"
+	if out != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, out)
+	}
+
+	cleanup()
+}
+
+func TestTreeDetailsAndJSON(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		"small.txt": "x",
+		"big.txt":   strings.Repeat("x", 100),
+	})
+
+	out, _ := dir.Tree(filic.TreeOptions{Size: true, Mode: true, Sort: filic.SortBySize})
+	lines := strings.Split(out, "\n")
+	if lines[1] != "├── [-rw-r--r--          1]  small.txt" {
+		t.Errorf("Unexpected line %q", lines[1])
+	}
+	if lines[2] != "└── [-rw-r--r--        100]  big.txt" {
+		t.Errorf("Unexpected line %q", lines[2])
+	}
+
+	root, err := dir.TreeNode(filic.TreeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := root.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded filic.TreeNode
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Children) != 2 || decoded.Children[0].Name != "big.txt" || decoded.Children[0].Size != 100 {
+		t.Errorf("Unexpected decoded tree %+v", decoded)
+	}
+
+	cleanup()
+}
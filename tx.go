@@ -0,0 +1,779 @@
+package filic
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// txDirName is the directory, inside the directory a transaction runs on,
+// holding staged content and journals.
+const txDirName = ".filic-tx"
+
+// txOp is a single staged operation, as recorded in the journal.
+type txOp struct {
+	Kind   string `json:"kind"`
+	Path   string `json:"path"`
+	To     string `json:"to,omitempty"`
+	Staged string `json:"staged,omitempty"`
+	Mode   uint32 `json:"mode,omitempty"`
+}
+
+// Tx is a transaction staging changes to several entries of a directory so
+// that they are applied all together or not at all.
+//
+// Changes are staged inside the directory and only applied by Commit. The
+// commit first durably writes a journal of the changes; if it is interrupted
+// after that, for example by a crash, the next call to Begin or Recover on
+// the directory finishes it. An interrupted transaction that didn't reach
+// its journal is discarded instead. Transactions of this process that are
+// still open are left alone, but those of other processes are not, so
+// processes must not run transactions on the same directory concurrently.
+type Tx struct {
+	dir     *Directory
+	id      string
+	staging string
+	ops     []txOp
+	done    bool
+}
+
+// Begin starts a transaction on the directory, after recovering any
+// transaction that was interrupted before.
+func (d *Directory) Begin() (*Tx, error) {
+	if err := d.Recover(); err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	tx := &Tx{dir: d, id: hex.EncodeToString(id)}
+	tx.staging = path.Join(d.Path, txDirName, tx.id)
+
+	// registered before the staging directory exists, so that a
+	// concurrent Recover never sees it unregistered
+	setTxOpen(tx.staging, true)
+	if err := os.MkdirAll(tx.staging, 0755); err != nil {
+		setTxOpen(tx.staging, false)
+		return nil, newPathError("begin", tx.staging, err)
+	}
+
+	return tx, nil
+}
+
+// openTxs holds the staging directories, as absolute paths, of the
+// transactions of this process that are not done yet. Recover leaves them
+// alone.
+var (
+	openTxsMu sync.Mutex
+	openTxs   = map[string]bool{}
+)
+
+func setTxOpen(staging string, open bool) {
+	if abs, err := filepath.Abs(staging); err == nil {
+		staging = abs
+	}
+
+	openTxsMu.Lock()
+	defer openTxsMu.Unlock()
+	if open {
+		openTxs[staging] = true
+	} else {
+		delete(openTxs, staging)
+	}
+}
+
+func isTxOpen(staging string) bool {
+	if abs, err := filepath.Abs(staging); err == nil {
+		staging = abs
+	}
+
+	openTxsMu.Lock()
+	defer openTxsMu.Unlock()
+	return openTxs[staging]
+}
+
+// path validates name and returns it cleaned, relative to the directory.
+func (tx *Tx) path(op, name string) (string, error) {
+	if tx.done {
+		return "", &PathError{Op: op, Path: tx.dir.Join(name), Err: ErrTxDone}
+	}
+	if _, err := tx.dir.child(op, name); err != nil {
+		return "", err
+	}
+
+	rel := strings.TrimPrefix(path.Clean("/"+name), "/")
+	if rel == txDirName || strings.HasPrefix(rel, txDirName+"/") {
+		return "", &PathError{Op: op, Path: tx.dir.Join(name), Err: ErrOutsideRoot}
+	}
+	return rel, nil
+}
+
+// Write stages writing data to the file name, creating it with 0644
+// permissions (rw-r--r--) if needed, along with its parent directories.
+func (tx *Tx) Write(name string, data []byte) error {
+	rel, err := tx.path("write", name)
+	if err != nil {
+		return err
+	}
+
+	staged := strconv.Itoa(len(tx.ops))
+	stagedPath := path.Join(tx.staging, staged)
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(tx.dir.Join(rel)); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	err = atomicWriteFile(stagedPath, mode, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	tx.ops = append(tx.ops, txOp{Kind: "write", Path: rel, Staged: staged, Mode: uint32(mode)})
+	return nil
+}
+
+// CreateDir stages creating the directory name, along with its parents.
+func (tx *Tx) CreateDir(name string) error {
+	rel, err := tx.path("mkdir", name)
+	if err != nil {
+		return err
+	}
+	tx.ops = append(tx.ops, txOp{Kind: "mkdir", Path: rel})
+	return nil
+}
+
+// Delete stages deleting the entry name, including the contents of
+// directories. Deleting an entry that doesn't exist is not an error.
+func (tx *Tx) Delete(name string) error {
+	rel, err := tx.path("delete", name)
+	if err != nil {
+		return err
+	}
+	tx.ops = append(tx.ops, txOp{Kind: "delete", Path: rel})
+	return nil
+}
+
+// Rename stages renaming the entry from to to, replacing an existing entry
+// of the same type at to.
+func (tx *Tx) Rename(from, to string) error {
+	relFrom, err := tx.path("rename", from)
+	if err != nil {
+		return err
+	}
+	relTo, err := tx.path("rename", to)
+	if err != nil {
+		return err
+	}
+	tx.ops = append(tx.ops, txOp{Kind: "rename", Path: relFrom, To: relTo})
+	return nil
+}
+
+// Commit applies the staged changes in the order they were staged. The
+// changes are checked against the directory first, and nothing is applied
+// if one of them cannot be. If applying a change still fails, the changes
+// applied before it are undone.
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return &PathError{Op: "commit", Path: tx.dir.Path, Err: ErrTxDone}
+	}
+	tx.done = true
+	defer setTxOpen(tx.staging, false)
+
+	if err := tx.validate(); err != nil {
+		os.RemoveAll(tx.staging)
+		return err
+	}
+
+	journal, err := json.Marshal(tx.ops)
+	if err != nil {
+		return err
+	}
+
+	// once the journal is on disk the transaction will be completed or
+	// undone, by this call or by recovery
+	journalPath := tx.journalPath()
+	err = atomicWriteFile(journalPath, 0644, func(w io.Writer) error {
+		_, err := w.Write(journal)
+		return err
+	})
+	if err != nil {
+		os.RemoveAll(tx.staging)
+		return err
+	}
+	syncDir(path.Dir(journalPath))
+
+	if err := tx.dir.rollForward(tx.id, tx.ops, 0, false); err != nil {
+		if rbErr := tx.dir.rollBack(tx.id); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// validate checks that every staged operation can be applied to the
+// directory as the operations before it will leave it.
+func (tx *Tx) validate() error {
+	view := &txView{dir: tx.dir, entries: map[string]string{}}
+
+	for _, op := range tx.ops {
+		var err error
+		switch op.Kind {
+		case "write":
+			err = view.write(op.Path)
+		case "mkdir":
+			err = view.mkdir(op.Path)
+		case "delete":
+			view.set(op.Path, "")
+		case "rename":
+			err = view.rename(op.Path, op.To)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// txView tracks the types of the entries of a directory as staged
+// operations will leave them: "file", "dir", or "" for a missing entry.
+// Entries the operations don't touch are looked up on disk.
+type txView struct {
+	dir     *Directory
+	entries map[string]string
+}
+
+func (v *txView) typeOf(rel string) string {
+	if t, ok := v.entries[rel]; ok {
+		return t
+	}
+	if parent := path.Dir(rel); parent != "." && v.typeOf(parent) != "dir" {
+		return ""
+	}
+
+	info, err := os.Lstat(v.dir.Join(rel))
+	switch {
+	case err != nil:
+		return ""
+	case info.IsDir():
+		return "dir"
+	}
+	return "file"
+}
+
+// set records the type of rel, forgetting what was recorded below it.
+func (v *txView) set(rel, t string) {
+	for name := range v.entries {
+		if strings.HasPrefix(name, rel+"/") {
+			delete(v.entries, name)
+		}
+	}
+	v.entries[rel] = t
+}
+
+// parents checks that the parents of rel are or can be made directories.
+func (v *txView) parents(rel string) error {
+	parent := path.Dir(rel)
+	if parent == "." {
+		return nil
+	}
+	if err := v.parents(parent); err != nil {
+		return err
+	}
+	if v.typeOf(parent) == "file" {
+		return &PathError{Op: "commit", Path: v.dir.Join(parent), Err: ErrNotDirectory}
+	}
+	v.entries[parent] = "dir"
+	return nil
+}
+
+func (v *txView) write(rel string) error {
+	if v.typeOf(rel) == "dir" {
+		return &PathError{Op: "commit", Path: v.dir.Join(rel), Err: ErrIsDirectory}
+	}
+	if err := v.parents(rel); err != nil {
+		return err
+	}
+	v.set(rel, "file")
+	return nil
+}
+
+func (v *txView) mkdir(rel string) error {
+	if v.typeOf(rel) == "file" {
+		return &PathError{Op: "commit", Path: v.dir.Join(rel), Err: ErrNotDirectory}
+	}
+	if err := v.parents(rel); err != nil {
+		return err
+	}
+	if v.typeOf(rel) != "dir" {
+		v.set(rel, "dir")
+	}
+	return nil
+}
+
+func (v *txView) rename(from, to string) error {
+	t := v.typeOf(from)
+	switch dest := v.typeOf(to); {
+	case t == "":
+		return &PathError{Op: "commit", Path: v.dir.Join(from), Err: ErrNotExist}
+	case to == from || strings.HasPrefix(to, from+"/"):
+		return &PathError{Op: "commit", Path: v.dir.Join(to), Err: errors.New("cannot rename an entry into itself")}
+	case t == "file" && dest == "dir":
+		return &PathError{Op: "commit", Path: v.dir.Join(to), Err: ErrIsDirectory}
+	case t == "dir" && dest == "file":
+		return &PathError{Op: "commit", Path: v.dir.Join(to), Err: ErrNotDirectory}
+	}
+	if err := v.parents(to); err != nil {
+		return err
+	}
+
+	// entries known below from move along
+	moved := map[string]string{}
+	for name, nt := range v.entries {
+		if rest, ok := strings.CutPrefix(name, from+"/"); ok {
+			moved[path.Join(to, rest)] = nt
+		}
+	}
+	v.set(from, "")
+	v.set(to, t)
+	for name, nt := range moved {
+		v.entries[name] = nt
+	}
+	return nil
+}
+
+// Rollback discards the staged changes.
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return &PathError{Op: "rollback", Path: tx.dir.Path, Err: ErrTxDone}
+	}
+	tx.done = true
+	defer setTxOpen(tx.staging, false)
+	return newPathError("rollback", tx.staging, os.RemoveAll(tx.staging))
+}
+
+func (tx *Tx) journalPath() string {
+	return path.Join(tx.dir.Path, txDirName, tx.id+".journal")
+}
+
+// txFileSuffixes are the suffixes of the files a transaction keeps next to
+// its staging directory, besides its journal.
+var txFileSuffixes = []string{".progress", ".undo", ".rollback"}
+
+// Recover completes transactions on the directory whose commit was
+// interrupted after writing their journal, and discards the staged changes
+// of transactions that never committed, except for the transactions of
+// this process that are still open. A transaction whose changes can no
+// longer be applied, or that was being undone, is undone instead. Begin
+// calls it automatically.
+func (d *Directory) Recover() error {
+	txDir := path.Join(d.Path, txDirName)
+
+	names, err := NewDirectory(txDir).List()
+	if errors.Is(err, ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	journals := map[string]bool{}
+	for _, name := range names {
+		if id, ok := strings.CutSuffix(name, ".journal"); ok {
+			journals[id] = true
+		}
+	}
+
+	for _, name := range names {
+		id := name
+		for _, suffix := range txFileSuffixes {
+			id = strings.TrimSuffix(id, suffix)
+		}
+
+		switch {
+		case isTxOpen(path.Join(txDir, id)):
+			// still staging or committing
+
+		case strings.HasSuffix(name, ".journal"):
+			if err := d.recoverTx(strings.TrimSuffix(name, ".journal")); err != nil {
+				return err
+			}
+
+		case journals[id]:
+			// handled with its journal
+
+		default:
+			if err := NewEntity(path.Join(txDir, name)).Delete(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// recoverTx completes or undoes the journaled transaction id.
+func (d *Directory) recoverTx(id string) error {
+	txDir := path.Join(d.Path, txDirName)
+
+	if _, err := os.Lstat(path.Join(txDir, id+".rollback")); err == nil {
+		return d.rollBack(id)
+	}
+
+	journalPath := path.Join(txDir, id+".journal")
+	data, err := os.ReadFile(journalPath)
+	if err != nil {
+		return newPathError("recover", journalPath, err)
+	}
+
+	var ops []txOp
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return newPathError("recover", journalPath, err)
+	}
+
+	applied, err := readTxProgress(path.Join(txDir, id+".progress"))
+	if err != nil {
+		return err
+	}
+
+	if err := d.rollForward(id, ops, applied, true); err != nil {
+		// the failed operation would fail again on every recovery
+		if rbErr := d.rollBack(id); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+	}
+	return nil
+}
+
+// txUndo is a record of the undo log of a transaction, written before the
+// change it describes is made.
+type txUndo struct {
+	// Kind is "create" for an entry that didn't exist, "backup" for an
+	// entry moved or linked to Backup in the staging directory, and
+	// "rename" for an entry renamed to To.
+	Kind   string `json:"kind"`
+	Path   string `json:"path"`
+	To     string `json:"to,omitempty"`
+	Backup string `json:"backup,omitempty"`
+}
+
+// txUndoLog appends records to the undo log of a transaction.
+type txUndoLog struct {
+	file *os.File
+}
+
+func (l *txUndoLog) record(u txUndo) error {
+	line, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return newPathError("commit", l.file.Name(), err)
+	}
+	return newPathError("commit", l.file.Name(), l.file.Sync())
+}
+
+// rollForward applies ops of the transaction id, starting at index from,
+// then removes the transaction's files. Progress is recorded after every
+// operation so that recovery doesn't apply an operation twice; the
+// operation in flight during a crash is applied again, which every kind of
+// operation tolerates. When replaying a journal, that operation may find
+// its staged file or renamed entry already moved; otherwise that is an
+// error. What the operations replace or remove is kept in the staging
+// directory until the end, so that rollBack can restore it.
+func (d *Directory) rollForward(id string, ops []txOp, from int, replay bool) error {
+	txDir := path.Join(d.Path, txDirName)
+	staging := path.Join(txDir, id)
+	progressPath := path.Join(txDir, id+".progress")
+	undoPath := path.Join(txDir, id+".undo")
+
+	progress, err := os.OpenFile(progressPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return newPathError("commit", progressPath, err)
+	}
+	defer progress.Close()
+
+	undoFile, err := os.OpenFile(undoPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return newPathError("commit", undoPath, err)
+	}
+	defer undoFile.Close()
+	undo := &txUndoLog{file: undoFile}
+
+	for i := from; i < len(ops); i++ {
+		if err := d.applyTxOp(staging, i, ops[i], replay && i == from, undo); err != nil {
+			return err
+		}
+
+		if _, err := progress.WriteString(strconv.Itoa(i+1) + "\n"); err != nil {
+			return newPathError("commit", progressPath, err)
+		}
+		if err := progress.Sync(); err != nil {
+			return newPathError("commit", progressPath, err)
+		}
+	}
+
+	// the journal goes first: without it, leftovers are simply discarded
+	if err := os.Remove(path.Join(txDir, id+".journal")); err != nil {
+		return newPathError("commit", txDir, err)
+	}
+	progress.Close()
+	undoFile.Close()
+	os.Remove(progressPath)
+	os.Remove(undoPath)
+	return newPathError("commit", staging, os.RemoveAll(staging))
+}
+
+// applyTxOp applies the operation at index i, recording how to undo it.
+// Operations are written so that applying one again after it completed
+// has no effect; reapplied reports whether that may be the case.
+func (d *Directory) applyTxOp(staging string, i int, op txOp, reapplied bool, undo *txUndoLog) error {
+	target := d.Join(op.Path)
+	backup := strconv.Itoa(i) + ".backup"
+
+	switch op.Kind {
+	case "write":
+		staged := path.Join(staging, op.Staged)
+		if _, err := os.Lstat(staged); err != nil {
+			if reapplied && errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return newPathError("commit", staged, err)
+		}
+		if err := d.makeTxDirs(path.Dir(op.Path), undo); err != nil {
+			return err
+		}
+
+		// the previous content is linked rather than moved, so the file
+		// is replaced atomically
+		if err := d.backupTxEntry(staging, op.Path, backup, true, undo); err != nil {
+			return err
+		}
+		if _, err := os.Lstat(target); errors.Is(err, os.ErrNotExist) {
+			if err := undo.record(txUndo{Kind: "create", Path: op.Path}); err != nil {
+				return err
+			}
+		}
+		return newPathError("commit", target, os.Rename(staged, target))
+
+	case "mkdir":
+		return d.makeTxDirs(op.Path, undo)
+
+	case "delete":
+		return d.backupTxEntry(staging, op.Path, backup, false, undo)
+
+	case "rename":
+		if _, err := os.Lstat(target); err != nil {
+			if reapplied && errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return newPathError("commit", target, err)
+		}
+		to := d.Join(op.To)
+		if err := d.makeTxDirs(path.Dir(op.To), undo); err != nil {
+			return err
+		}
+		if err := d.backupTxEntry(staging, op.To, backup, false, undo); err != nil {
+			return err
+		}
+		if err := undo.record(txUndo{Kind: "rename", Path: op.Path, To: op.To}); err != nil {
+			return err
+		}
+		return newPathError("commit", target, os.Rename(target, to))
+	}
+
+	return newPathError("commit", target, errors.New("unknown transaction operation "+op.Kind))
+}
+
+// makeTxDirs creates the directory rel along with its parents, recording
+// the ones it creates.
+func (d *Directory) makeTxDirs(rel string, undo *txUndoLog) error {
+	if rel == "." {
+		return nil
+	}
+	if err := d.makeTxDirs(path.Dir(rel), undo); err != nil {
+		return err
+	}
+
+	p := d.Join(rel)
+	info, err := os.Lstat(p)
+	if err == nil {
+		if !info.IsDir() {
+			return &PathError{Op: "commit", Path: p, Err: ErrNotDirectory}
+		}
+		return nil
+	}
+
+	if err := undo.record(txUndo{Kind: "create", Path: rel}); err != nil {
+		return err
+	}
+	if err := os.Mkdir(p, 0755); err != nil && !errors.Is(err, os.ErrExist) {
+		return newPathError("commit", p, err)
+	}
+	return nil
+}
+
+// backupTxEntry moves the entry rel, if it exists, to backup in the
+// staging directory. With link, a file is hard linked instead, leaving it
+// in place.
+func (d *Directory) backupTxEntry(staging, rel, backup string, link bool, undo *txUndoLog) error {
+	p := d.Join(rel)
+	info, err := os.Lstat(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return newPathError("commit", p, err)
+	}
+
+	backupPath := path.Join(staging, backup)
+	if _, err := os.Lstat(backupPath); err == nil {
+		// backed up before an interruption
+		return nil
+	}
+
+	if err := undo.record(txUndo{Kind: "backup", Path: rel, Backup: backup}); err != nil {
+		return err
+	}
+	if link && info.Mode().IsRegular() && os.Link(p, backupPath) == nil {
+		return nil
+	}
+	return newPathError("commit", p, os.Rename(p, backupPath))
+}
+
+// rollBack undoes the changes the transaction id applied, in reverse order,
+// then removes the transaction's files. A marker makes recovery finish an
+// interrupted roll back rather than apply the transaction again.
+func (d *Directory) rollBack(id string) error {
+	txDir := path.Join(d.Path, txDirName)
+	staging := path.Join(txDir, id)
+	markerPath := path.Join(txDir, id+".rollback")
+	undoPath := path.Join(txDir, id+".undo")
+
+	if err := os.WriteFile(markerPath, nil, 0644); err != nil {
+		return newPathError("rollback", markerPath, err)
+	}
+	syncDir(txDir)
+
+	records, err := readTxUndo(undoPath)
+	if err != nil {
+		return err
+	}
+
+	for i := len(records) - 1; i >= 0; i-- {
+		if err := d.undoTxChange(staging, records[i]); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(path.Join(txDir, id+".journal")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return newPathError("rollback", txDir, err)
+	}
+	os.Remove(path.Join(txDir, id+".progress"))
+	os.Remove(undoPath)
+	os.Remove(markerPath)
+	return newPathError("rollback", staging, os.RemoveAll(staging))
+}
+
+// undoTxChange reverts a single change. Like operations, undoing a change
+// again after it was undone has no effect.
+func (d *Directory) undoTxChange(staging string, u txUndo) error {
+	p := d.Join(u.Path)
+
+	switch u.Kind {
+	case "create":
+		return newPathError("rollback", p, os.RemoveAll(p))
+
+	case "backup":
+		backupPath := path.Join(staging, u.Backup)
+		if _, err := os.Lstat(backupPath); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err := os.RemoveAll(p); err != nil {
+			return newPathError("rollback", p, err)
+		}
+		return newPathError("rollback", p, os.Rename(backupPath, p))
+
+	case "rename":
+		to := d.Join(u.To)
+		if _, err := os.Lstat(p); err == nil {
+			return nil
+		}
+		if _, err := os.Lstat(to); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return newPathError("rollback", to, os.Rename(to, p))
+	}
+
+	return newPathError("rollback", p, errors.New("unknown transaction change "+u.Kind))
+}
+
+// readTxUndo returns the records of an undo log. A torn last record is
+// ignored: its change was never made.
+func readTxUndo(p string) ([]txUndo, error) {
+	file, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, newPathError("rollback", p, err)
+	}
+	defer file.Close()
+
+	var records []txUndo
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var u txUndo
+		if err := json.Unmarshal(scanner.Bytes(), &u); err == nil {
+			records = append(records, u)
+		}
+	}
+	return records, newPathError("rollback", p, scanner.Err())
+}
+
+// readTxProgress returns how many operations a transaction recorded as
+// applied. A torn last line is ignored.
+func readTxProgress(p string) (int, error) {
+	file, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, newPathError("recover", p, err)
+	}
+	defer file.Close()
+
+	applied := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if n, err := strconv.Atoi(scanner.Text()); err == nil && n > applied {
+			applied = n
+		}
+	}
+	return applied, nil
+}
+
+// syncDir flushes the entries of a directory to disk, making renames into
+// it durable. Errors are ignored, as not every platform supports it.
+func syncDir(dir string) {
+	if file, err := os.Open(dir); err == nil {
+		file.Sync()
+		file.Close()
+	}
+}
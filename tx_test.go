@@ -0,0 +1,264 @@
+package filic_test
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func TestTxCommit(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		"config.json": `{"v": 1}`,
+		"old.txt":     "old",
+		"obsolete":    "x",
+	})
+
+	tx, err := dir.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx.Write("config.json", []byte(`{"v": 2}`))
+	tx.Write("schema/v2.json", []byte(`{}`))
+	tx.Rename("old.txt", "archive/old.txt")
+	tx.Delete("obsolete")
+	tx.CreateDir("empty")
+
+	// nothing is visible before the commit
+	content, _ := filic.NewFile(dir.Join("config.json")).ReadString()
+	if content != `{"v": 1}` {
+		t.Errorf("Staged changes should not be visible, got %q", content)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string]string{
+		"config.json":     `{"v": 2}`,
+		"schema/v2.json":  `{}`,
+		"archive/old.txt": "old",
+	} {
+		content, err := filic.NewFile(dir.Join(name)).ReadString()
+		if err != nil {
+			t.Error(err)
+		}
+		if content != expected {
+			t.Errorf("Expected %q in %v, got %q", expected, name, content)
+		}
+	}
+
+	if filic.NewEntity(dir.Join("obsolete")).Exists() || filic.NewEntity(dir.Join("old.txt")).Exists() {
+		t.Error("Deleted and renamed entries should be gone")
+	}
+	if !filic.NewEntity(dir.Join("empty")).Exists() {
+		t.Error("Directory should have been created")
+	}
+
+	names, _ := filic.NewDirectory(dir.Join(".filic-tx")).List()
+	if len(names) != 0 {
+		t.Errorf("Expected transaction files to be cleaned up, got %v", names)
+	}
+
+	if err := tx.Write("late.txt", nil); !errors.Is(err, filic.ErrTxDone) {
+		t.Errorf("Expected ErrTxDone, got %v", err)
+	}
+
+	cleanup()
+}
+
+func TestTxRollbackAndRecoverUncommitted(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{"a.txt": "a"})
+
+	tx, err := dir.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Write("a.txt", []byte("changed"))
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the staged changes of a process that died before committing are
+	// discarded by the next transaction
+	os.MkdirAll(dir.Join(".filic-tx/abandoned"), 0755)
+	os.WriteFile(dir.Join(".filic-tx/abandoned/0"), []byte("abandoned"), 0644)
+
+	next, err := dir.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	next.Rollback()
+
+	content, _ := filic.NewFile(dir.Join("a.txt")).ReadString()
+	if content != "a" {
+		t.Errorf("Expected %q, got %q", "a", content)
+	}
+
+	names, _ := filic.NewDirectory(dir.Join(".filic-tx")).List()
+	if len(names) != 0 {
+		t.Errorf("Expected abandoned transaction to be discarded, got %v", names)
+	}
+
+	if err := tx.Rename("a.txt", "b.txt"); !errors.Is(err, filic.ErrTxDone) {
+		t.Errorf("Expected ErrTxDone, got %v", err)
+	}
+
+	escaping, _ := dir.Begin()
+	if err := escaping.Write("../outside.txt", nil); !errors.Is(err, filic.ErrOutsideRoot) {
+		t.Errorf("Expected ErrOutsideRoot, got %v", err)
+	}
+	escaping.Rollback()
+
+	cleanup()
+}
+
+func TestTxConcurrentBegin(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{"a.txt": "a"})
+
+	first, _ := dir.Begin()
+	first.Write("a.txt", []byte("first"))
+
+	// recovery must leave the staged changes of open transactions alone
+	second, err := dir.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.Write("b.txt", []byte("second"))
+
+	if err := first.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string]string{"a.txt": "first", "b.txt": "second"} {
+		content, _ := filic.NewFile(dir.Join(name)).ReadString()
+		if content != expected {
+			t.Errorf("Expected %q in %v, got %q", expected, name, content)
+		}
+	}
+
+	// content missing from the staging directory fails the commit
+	tx, _ := dir.Begin()
+	tx.Write("a.txt", []byte("lost"))
+	os.RemoveAll(dir.Join(".filic-tx"))
+	os.MkdirAll(dir.Join(".filic-tx"), 0755)
+	if err := tx.Commit(); !errors.Is(err, filic.ErrNotExist) {
+		t.Errorf("Expected ErrNotExist, got %v", err)
+	}
+
+	cleanup()
+}
+
+func TestTxRecoverInterruptedCommit(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{"a.txt": "old a", "b.txt": "b"})
+
+	// the state left by a commit interrupted after its journal was written
+	// and its first operation was applied
+	txDir := dir.Join(".filic-tx")
+	os.MkdirAll(path.Join(txDir, "abc"), 0755)
+	os.WriteFile(path.Join(txDir, "abc", "1"), []byte("new c"), 0644)
+	os.WriteFile(path.Join(txDir, "abc.journal"), []byte(`[
+		{"kind": "rename", "path": "a.txt", "to": "moved.txt"},
+		{"kind": "write", "path": "c.txt", "staged": "1", "mode": 420},
+		{"kind": "delete", "path": "b.txt"}
+	]`), 0644)
+	os.WriteFile(path.Join(txDir, "abc.progress"), []byte("1\n"), 0644)
+	os.Rename(dir.Join("a.txt"), dir.Join("moved.txt"))
+
+	// a new file at the old name must not be renamed again
+	os.WriteFile(dir.Join("a.txt"), []byte("recreated"), 0644)
+
+	if err := dir.Recover(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string]string{
+		"moved.txt": "old a",
+		"a.txt":     "recreated",
+		"c.txt":     "new c",
+	} {
+		content, _ := filic.NewFile(dir.Join(name)).ReadString()
+		if content != expected {
+			t.Errorf("Expected %q in %v, got %q", expected, name, content)
+		}
+	}
+
+	if filic.NewEntity(dir.Join("b.txt")).Exists() {
+		t.Error("b.txt should have been deleted")
+	}
+
+	cleanup()
+}
+
+func TestTxCommitValidatesAndRollsBack(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{"ok.txt": "old", "conf/app.json": "{}"})
+
+	// a write over a directory is refused before anything is applied
+	tx, _ := dir.Begin()
+	tx.Write("ok.txt", []byte("new"))
+	tx.Write("conf", []byte("not a directory"))
+	if err := tx.Commit(); !errors.Is(err, filic.ErrIsDirectory) {
+		t.Errorf("Expected ErrIsDirectory, got %v", err)
+	}
+
+	content, _ := filic.NewFile(dir.Join("ok.txt")).ReadString()
+	if content != "old" {
+		t.Errorf("Expected ok.txt to be left alone, got %q", content)
+	}
+
+	// a journaled commit that cannot be finished is undone by recovery
+	txDir := dir.Join(".filic-tx")
+	os.MkdirAll(path.Join(txDir, "abc"), 0755)
+	os.WriteFile(path.Join(txDir, "abc", "0"), []byte("new"), 0644)
+	os.WriteFile(path.Join(txDir, "abc", "3"), []byte("replaced"), 0644)
+	os.WriteFile(path.Join(txDir, "abc", "4"), []byte("not a directory"), 0644)
+	os.WriteFile(dir.Join("a.txt"), []byte("a"), 0644)
+	os.WriteFile(path.Join(txDir, "abc.journal"), []byte(`[
+		{"kind": "write", "path": "ok.txt", "staged": "0", "mode": 420},
+		{"kind": "delete", "path": "conf"},
+		{"kind": "rename", "path": "a.txt", "to": "new/moved.txt"},
+		{"kind": "write", "path": "a.txt", "staged": "3", "mode": 420},
+		{"kind": "write", "path": "ok.txt/nested", "staged": "4", "mode": 420}
+	]`), 0644)
+
+	if err := dir.Recover(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string]string{
+		"ok.txt":        "old",
+		"conf/app.json": "{}",
+		"a.txt":         "a",
+	} {
+		content, _ := filic.NewFile(dir.Join(name)).ReadString()
+		if content != expected {
+			t.Errorf("Expected %q in %v, got %q", expected, name, content)
+		}
+	}
+	if filic.NewEntity(dir.Join("new")).Exists() {
+		t.Error("Expected created entries to be removed")
+	}
+
+	names, _ := filic.NewDirectory(txDir).List()
+	if len(names) != 0 {
+		t.Errorf("Expected the transaction files to be removed, got %v", names)
+	}
+
+	cleanup()
+}
@@ -0,0 +1,238 @@
+package filic
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// diffOp is one line of an edit script: ' ' keeps a line, '-' removes a
+// line of the old text and '+' inserts a line of the new text.
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// UnifiedDiff returns the differences between oldText and newText in the
+// unified diff format, with contextLines lines of context around every
+// change. oldName and newName are used in the "---" and "+++" headers. An
+// empty string is returned when the texts are equal.
+func UnifiedDiff(oldName, newName, oldText, newText string, contextLines int) string {
+	if oldText == newText {
+		return ""
+	}
+
+	ops := diffLines(splitLines(oldText), splitLines(newText))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+
+	// oldLines[i] and newLines[i] count the lines before ops[i]
+	oldLines := make([]int, len(ops)+1)
+	newLines := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldLines[i+1], newLines[i+1] = oldLines[i], newLines[i]
+		if op.kind != '+' {
+			oldLines[i+1]++
+		}
+		if op.kind != '-' {
+			newLines[i+1]++
+		}
+	}
+
+	for i := 0; i < len(ops); i++ {
+		if ops[i].kind == ' ' {
+			continue
+		}
+
+		// extend the hunk while the next change is close enough for the
+		// context of both to overlap or touch
+		last := i
+		for j := i + 1; j < len(ops) && j-last <= 2*contextLines+1; j++ {
+			if ops[j].kind != ' ' {
+				last = j
+			}
+		}
+
+		start := max(i-contextLines, 0)
+		end := min(last+1+contextLines, len(ops))
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n",
+			hunkRange(oldLines[start], oldLines[end]-oldLines[start]),
+			hunkRange(newLines[start], newLines[end]-newLines[start]))
+
+		for _, op := range ops[start:end] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		i = end - 1
+	}
+
+	return out.String()
+}
+
+// hunkRange formats the "start,count" part of a hunk header, where start is
+// the 0-based line number before the hunk.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines splits text after every newline, keeping the newlines.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes a shortest edit script turning a into b using the
+// linear space variant of the Myers difference algorithm, which splits the
+// texts at the middle of an optimal path and recurses on both halves.
+// Within a run of changes, removed lines come before inserted ones.
+func diffLines(a, b []string) []diffOp {
+	size := len(a) + len(b) + 2
+	ld := &lineDiff{a: a, b: b, vf: make([]int, 4*size+1), vb: make([]int, 4*size+1)}
+	ld.diff(0, len(a), 0, len(b))
+
+	ops := ld.ops
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(ops) && ops[j].kind != ' ' {
+			j++
+		}
+		run := ops[i:j]
+		sort.SliceStable(run, func(x, y int) bool { return run[x].kind == '-' && run[y].kind == '+' })
+		i = j
+	}
+	return ops
+}
+
+// lineDiff holds the state of diffLines: the texts, the edit script built
+// so far and the furthest reaching paths, shared by every step.
+type lineDiff struct {
+	a, b   []string
+	ops    []diffOp
+	vf, vb []int
+}
+
+// diff appends the edit script turning a[aLo:aHi] into b[bLo:bHi].
+func (ld *lineDiff) diff(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && ld.a[aLo] == ld.b[bLo] {
+		ld.ops = append(ld.ops, diffOp{' ', ld.a[aLo]})
+		aLo++
+		bLo++
+	}
+	suffix := aHi
+	for aHi > aLo && bHi > bLo && ld.a[aHi-1] == ld.b[bHi-1] {
+		aHi--
+		bHi--
+	}
+
+	switch {
+	case aLo == aHi:
+		for _, line := range ld.b[bLo:bHi] {
+			ld.ops = append(ld.ops, diffOp{'+', line})
+		}
+	case bLo == bHi:
+		for _, line := range ld.a[aLo:aHi] {
+			ld.ops = append(ld.ops, diffOp{'-', line})
+		}
+	default:
+		x, y := ld.split(aLo, aHi, bLo, bHi)
+		ld.diff(aLo, x, bLo, y)
+		ld.diff(x, aHi, y, bHi)
+	}
+
+	for _, line := range ld.a[aHi:suffix] {
+		ld.ops = append(ld.ops, diffOp{' ', line})
+	}
+}
+
+// split returns a point on a shortest path through a[aLo:aHi] and
+// b[bLo:bHi], found by searching from both ends until the paths meet. The
+// ranges must start and end with differing lines, so the point is never
+// one of the ends.
+func (ld *lineDiff) split(aLo, aHi, bLo, bHi int) (int, int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	offset := 2 * (n + m + 2)
+
+	// vf[offset+k] is the furthest x reached from the start on diagonal
+	// k = x-y, vb[offset+k] the smallest x reached from the end
+	vf, vb := ld.vf, ld.vb
+	vf[offset+1] = 0
+	vb[offset+delta+1] = n + 1
+
+	for d := 0; ; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && vf[offset+k-1] < vf[offset+k+1]) {
+				x = vf[offset+k+1]
+			} else {
+				x = vf[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && ld.a[aLo+x] == ld.b[bLo+y] {
+				x++
+				y++
+			}
+			vf[offset+k] = x
+
+			if odd && k >= delta-(d-1) && k <= delta+(d-1) && x >= vb[offset+k] {
+				return aLo + x, bLo + y
+			}
+		}
+
+		for kr := -d; kr <= d; kr += 2 {
+			k := kr + delta
+			var x int
+			if kr == -d || (kr != d && vb[offset+k+1]-1 < vb[offset+k-1]) {
+				x = vb[offset+k+1] - 1
+			} else {
+				x = vb[offset+k-1]
+			}
+			y := x - k
+			for x > 0 && y > 0 && ld.a[aLo+x-1] == ld.b[bLo+y-1] {
+				x--
+				y--
+			}
+			vb[offset+k] = x
+
+			if !odd && k >= -d && k <= d && x <= vf[offset+k] {
+				return aLo + x, bLo + y
+			}
+		}
+	}
+}
+
+// binarySniffLen is how much of the content isBinary looks at.
+const binarySniffLen = 8000
+
+// isBinary reports whether data looks like binary content, using the same
+// heuristic as git: a NUL byte within the first few kilobytes.
+func isBinary(data []byte) bool {
+	if len(data) > binarySniffLen {
+		data = data[:binarySniffLen]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
@@ -0,0 +1,163 @@
+package filic
+
+import (
+	"context"
+	"io/fs"
+	"sort"
+)
+
+// DiskUsage summarizes the size of a directory tree.
+type DiskUsage struct {
+	// ApparentSize is the sum of the sizes of the files and symbolic links.
+	ApparentSize int64 `json:"apparentSize"`
+	// AllocatedSize is the storage allocated to the tree, including the
+	// directories themselves, like reported by du.
+	AllocatedSize int64 `json:"allocatedSize"`
+	// Files counts files and symbolic links.
+	Files int `json:"files"`
+	// Dirs counts directories, not including the root of the tree.
+	Dirs int `json:"dirs"`
+}
+
+// ChildUsage is the disk usage of one direct child of a directory.
+type ChildUsage struct {
+	Name  string `json:"name"`
+	IsDir bool   `json:"isDir"`
+	DiskUsage
+}
+
+// FSStats describes the capacity and usage of a file system.
+type FSStats struct {
+	// Total is the size of the file system.
+	Total uint64 `json:"total"`
+	// Free is the space not in use, including space reserved for root.
+	Free uint64 `json:"free"`
+	// Available is the space available to unprivileged users.
+	Available uint64 `json:"available"`
+	// Inodes is the total number of inodes.
+	Inodes uint64 `json:"inodes"`
+	// FreeInodes is the number of inodes not in use.
+	FreeInodes uint64 `json:"freeInodes"`
+}
+
+// inodeSet remembers which hard linked files were already counted.
+type inodeSet map[[2]uint64]bool
+
+// seen reports whether info describes a hard linked file that was already
+// counted, marking it as counted otherwise.
+func (s inodeSet) seen(info fs.FileInfo) bool {
+	dev, ino, nlink, ok := fileIdentity(info)
+	if !ok || nlink < 2 || info.IsDir() {
+		return false
+	}
+
+	key := [2]uint64{dev, ino}
+	if s[key] {
+		return true
+	}
+	s[key] = true
+	return false
+}
+
+// add counts the entry described by info unless it is a hard link to a file
+// already counted.
+func (u *DiskUsage) add(info fs.FileInfo, inodes inodeSet) {
+	if inodes.seen(info) {
+		return
+	}
+
+	u.AllocatedSize += allocatedSize(info)
+	if info.IsDir() {
+		u.Dirs++
+		return
+	}
+	u.Files++
+	u.ApparentSize += info.Size()
+}
+
+// Size returns the disk usage of the whole tree below the directory.
+// Symbolic links are counted but not followed, and files with several hard
+// links inside the tree are only counted once.
+func (d *Directory) Size() (*DiskUsage, error) {
+	return d.SizeContext(context.Background())
+}
+
+// SizeContext is like Size but stops and returns the context's error once
+// ctx is cancelled.
+func (d *Directory) SizeContext(ctx context.Context) (*DiskUsage, error) {
+	usage := &DiskUsage{}
+	inodes := inodeSet{}
+
+	err := d.WalkContext(ctx, func(entity *Entity) error {
+		info, err := entity.lstat()
+		if err != nil {
+			return newPathError("size", entity.Path, err)
+		}
+		usage.add(info, inodes)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
+
+// Usage returns the disk usage of every direct child of the directory,
+// largest allocated size first, like `du -d 1 | sort -rn`. Hard links are
+// deduplicated across all children, so a file shared by two children is
+// counted in the first one only.
+func (d *Directory) Usage() ([]ChildUsage, error) {
+	return d.UsageContext(context.Background())
+}
+
+// UsageContext is like Usage but stops and returns the context's error once
+// ctx is cancelled.
+func (d *Directory) UsageContext(ctx context.Context) ([]ChildUsage, error) {
+	entries, err := readDirContext(ctx, d.Path)
+	if err != nil {
+		return nil, newPathError("usage", d.Path, err)
+	}
+
+	inodes := inodeSet{}
+	var children []ChildUsage
+	for _, entry := range entries {
+		entity := newListedEntity(d.Path, entry)
+
+		info, err := entity.lstat()
+		if err != nil {
+			return nil, newPathError("usage", entity.Path, err)
+		}
+
+		child := ChildUsage{Name: entry.Name(), IsDir: info.IsDir()}
+		if info.IsDir() {
+			// the directory's own blocks, but not the directory itself, count
+			child.AllocatedSize += allocatedSize(info)
+
+			err := NewDirectory(entity.Path).WalkContext(ctx, func(entity *Entity) error {
+				info, err := entity.lstat()
+				if err != nil {
+					return newPathError("usage", entity.Path, err)
+				}
+				child.add(info, inodes)
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			child.add(info, inodes)
+		}
+
+		children = append(children, child)
+	}
+
+	sort.SliceStable(children, func(i, j int) bool {
+		if children[i].AllocatedSize != children[j].AllocatedSize {
+			return children[i].AllocatedSize > children[j].AllocatedSize
+		}
+		return children[i].Name < children[j].Name
+	})
+
+	return children, nil
+}
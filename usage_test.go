@@ -0,0 +1,85 @@
+package filic_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func TestDirectorySize(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		"a.txt":       strings.Repeat("a", 100),
+		"sub/b.txt":   strings.Repeat("b", 50),
+		"sub/c/d.txt": strings.Repeat("d", 10),
+	})
+
+	// a hard link to a.txt must not be counted twice
+	if err := os.Link(dir.Join("a.txt"), dir.Join("sub/hardlink.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	usage, err := dir.Size()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if usage.ApparentSize != 160 {
+		t.Errorf("Expected apparent size 160, got %d", usage.ApparentSize)
+	}
+	if usage.Files != 3 {
+		t.Errorf("Expected 3 files, got %d", usage.Files)
+	}
+	if usage.Dirs != 2 {
+		t.Errorf("Expected 2 directories, got %d", usage.Dirs)
+	}
+	if usage.AllocatedSize <= 0 {
+		t.Errorf("Expected a positive allocated size, got %d", usage.AllocatedSize)
+	}
+
+	cleanup()
+}
+
+func TestDirectoryUsage(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		"small.txt":    "x",
+		"big/one.bin":  strings.Repeat("x", 64<<10),
+		"big/two.bin":  strings.Repeat("x", 64<<10),
+		"medium/m.bin": strings.Repeat("x", 16<<10),
+	})
+
+	children, err := dir.Usage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, child := range children {
+		names = append(names, child.Name)
+	}
+	expectNames(t, []string{"big", "medium", "small.txt"}, names)
+
+	if children[0].ApparentSize != 128<<10 || children[0].Files != 2 || !children[0].IsDir {
+		t.Errorf("Unexpected usage for big: %+v", children[0])
+	}
+
+	cleanup()
+}
+
+func TestFSStats(t *testing.T) {
+	dir := filic.NewDirectory(os.TempDir())
+
+	stats, err := dir.FSStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Total == 0 || stats.Available > stats.Total || stats.Free > stats.Total {
+		t.Errorf("Unexpected file system stats: %+v", stats)
+	}
+}
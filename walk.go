@@ -0,0 +1,154 @@
+package filic
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// SkipDir can be returned from a WalkFunc to skip the contents of the
+// directory it was called with. It is the same value as fs.SkipDir.
+var SkipDir = fs.SkipDir
+
+// WalkFunc is called by Walk for every entry below the walked directory.
+// Returning SkipDir for a directory skips its contents; any other error
+// stops the walk and is returned by Walk.
+type WalkFunc func(entity *Entity) error
+
+// Walk visits every file and directory below d in lexical order, calling fn
+// for each of them. Directories are visited before their contents. Symbolic
+// links are reported but never followed. The directory itself is not passed
+// to fn.
+func (d *Directory) Walk(fn WalkFunc) error {
+	return d.WalkContext(context.Background(), fn)
+}
+
+// WalkContext is like Walk but stops and returns the context's error once
+// ctx is cancelled.
+func (d *Directory) WalkContext(ctx context.Context, fn WalkFunc) error {
+	return d.WalkWithOptions(ctx, WalkOptions{}, fn)
+}
+
+// WalkOptions configures WalkWithOptions.
+type WalkOptions struct {
+	// Ignore, if set, excludes matching entities from the walk. Ignored
+	// directories are not descended into.
+	Ignore *IgnoreMatcher
+}
+
+// WalkWithOptions is like WalkContext but configured by opts.
+func (d *Directory) WalkWithOptions(ctx context.Context, opts WalkOptions, fn WalkFunc) error {
+	w := &walker{root: d.Path, ignore: opts.Ignore.forTraversal(), fn: fn}
+	return w.walk(ctx, d.Path)
+}
+
+// walker holds the state of a single walk.
+type walker struct {
+	root   string
+	ignore *IgnoreMatcher
+	fn     WalkFunc
+	// dirErr, if set, is called with the error reading a directory, and
+	// the walk goes on with the rest of the tree when it returns nil.
+	dirErr func(err error) error
+}
+
+func (w *walker) walk(ctx context.Context, dir string) error {
+	entries, err := readDirContext(ctx, dir)
+	if err != nil {
+		if w.dirErr != nil && ctx.Err() == nil {
+			return w.dirErr(newPathError("walk", dir, err))
+		}
+		return newPathError("walk", dir, err)
+	}
+
+	if w.ignore != nil && len(w.ignore.fileNames) > 0 {
+		err := w.ignore.loadDir(dir, relativePath(w.root, dir), w.ignore.fileNames)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		entity := newListedEntity(dir, entry)
+
+		// like git, symbolic links are matched as files
+		if w.ignore.Match(relativePath(w.root, entity.Path), entry.IsDir()) {
+			continue
+		}
+
+		err := w.fn(entity)
+		if errors.Is(err, SkipDir) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if err := w.walk(ctx, entity.Path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// readDirBatch is the number of entries read from a directory at a time
+// by readDirContext, bounding how long a cancelled read keeps running.
+const readDirBatch = 1024
+
+// readDirContext reads all entries of dir sorted by name like os.ReadDir,
+// checking ctx between batches.
+func readDirContext(ctx context.Context, dir string) ([]fs.DirEntry, error) {
+	file, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []fs.DirEntry
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		batch, err := file.ReadDir(readDirBatch)
+		entries = append(entries, batch...)
+
+		if errors.Is(err, io.EOF) || (err == nil && len(batch) == 0) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return entries, nil
+}
+
+// relativePath returns p relative to root, where p is a path produced by
+// walking root. The root itself is returned as ".".
+func relativePath(root, p string) string {
+	root, p = path.Clean(root), path.Clean(p)
+	if p == root {
+		return "."
+	}
+	if root == "/" {
+		return strings.TrimPrefix(p, "/")
+	}
+	return strings.TrimPrefix(p, root+"/")
+}
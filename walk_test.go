@@ -0,0 +1,111 @@
+package filic_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/henilmalaviya/filic"
+)
+
+// createTree creates the given files (with their content) below the temp
+// directory and returns it.
+func createTree(t *testing.T, files map[string]string) *filic.Directory {
+	t.Helper()
+
+	dir := filic.NewDirectory(getTempDirPath())
+	for name, content := range files {
+		p := dir.Join(name)
+		if err := os.MkdirAll(path.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestWalk(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		"b.txt":       "b",
+		"a/one.txt":   "1",
+		"a/c/two.txt": "2",
+	})
+
+	var visited []string
+	err := dir.Walk(func(entity *filic.Entity) error {
+		visited = append(visited, strings.TrimPrefix(entity.Path, dir.Path+"/"))
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	expected := []string{"a", "a/c", "a/c/two.txt", "a/one.txt", "b.txt"}
+	if len(visited) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, visited)
+	}
+	for i := range expected {
+		if visited[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, visited)
+		}
+	}
+
+	cleanup()
+}
+
+func TestWalkSkipDir(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		"skip/inner.txt": "x",
+		"keep.txt":       "y",
+	})
+
+	var visited []string
+	err := dir.Walk(func(entity *filic.Entity) error {
+		visited = append(visited, path.Base(entity.Path))
+		if path.Base(entity.Path) == "skip" {
+			return filic.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(visited) != 2 {
+		t.Errorf("Expected 2 visited entries, got %v", visited)
+	}
+
+	cleanup()
+}
+
+func TestWalkContextCancelled(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{"a.txt": "a"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := dir.WalkContext(ctx, func(entity *filic.Entity) error {
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	_, err = dir.ListContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	cleanup()
+}
@@ -0,0 +1,153 @@
+package filic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"sort"
+)
+
+// contentEquals reports whether the file at p holds exactly data. The sizes
+// are compared first and the content is then streamed in chunks, stopping
+// at the first difference. A missing file is reported as different.
+func contentEquals(p string, data []byte) (bool, error) {
+	file, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	if !info.Mode().IsRegular() || info.Size() != int64(len(data)) {
+		return false, nil
+	}
+
+	buf := make([]byte, 32<<10)
+	for offset := 0; offset < len(data); {
+		n, err := io.ReadFull(file, buf[:min(len(buf), len(data)-offset)])
+		if err != nil {
+			// the file shrank while being read
+			return false, nil
+		}
+		if !bytes.Equal(buf[:n], data[offset:offset+n]) {
+			return false, nil
+		}
+		offset += n
+	}
+
+	return true, nil
+}
+
+// WriteIfChanged writes data to the file like Write, unless the file already
+// holds exactly that data, in which case it is left untouched and keeps its
+// modification time. It reports whether the file was written.
+func (f *File) WriteIfChanged(data []byte) (bool, error) {
+	equal, err := contentEquals(f.Path, data)
+	if err != nil {
+		return false, newPathError("write", f.Path, err)
+	}
+	if equal {
+		return false, nil
+	}
+
+	if err := f.Write(data); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ApplyOptions configures Directory.ApplyTree.
+type ApplyOptions struct {
+	// Prune removes files below the directory that are not part of the
+	// applied tree.
+	Prune bool
+	// Ignore, if set, protects matching entries from being pruned.
+	Ignore *IgnoreMatcher
+}
+
+// ApplyReport lists what ApplyTree did. Paths are relative to the directory
+// and sorted.
+type ApplyReport struct {
+	// Changed lists the files that were created or rewritten.
+	Changed []string `json:"changed"`
+	// Unchanged lists the files that already had the right content.
+	Unchanged []string `json:"unchanged"`
+	// Removed lists the files deleted by pruning.
+	Removed []string `json:"removed"`
+}
+
+// ApplyTree writes a generated tree of files into the directory, keyed by
+// their path relative to it, rewriting only the files whose content differs.
+// Parent directories are created as needed. With opts.Prune, files that are
+// not part of the tree are removed. Paths escaping the directory are
+// rejected with ErrOutsideRoot before anything is written.
+func (d *Directory) ApplyTree(files map[string][]byte, opts ApplyOptions) (*ApplyReport, error) {
+	names := make([]string, 0, len(files))
+	wanted := make(map[string]bool, len(files))
+	for name := range files {
+		if _, err := d.child("apply", name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		wanted[path.Clean(name)] = true
+	}
+	sort.Strings(names)
+
+	report := &ApplyReport{}
+	for _, name := range names {
+		file := NewFile(d.Join(name))
+
+		parent := file.OpenParent()
+		if err := parent.Create(); err != nil {
+			return report, err
+		}
+
+		changed, err := file.WriteIfChanged(files[name])
+		if err != nil {
+			return report, err
+		}
+
+		if changed {
+			report.Changed = append(report.Changed, path.Clean(name))
+		} else {
+			report.Unchanged = append(report.Unchanged, path.Clean(name))
+		}
+	}
+
+	if !opts.Prune {
+		return report, nil
+	}
+
+	var stale []*Entity
+	err := d.WalkWithOptions(context.Background(), WalkOptions{Ignore: opts.Ignore}, func(entity *Entity) error {
+		if entity.entry.IsDir() {
+			return nil
+		}
+		rel := relativePath(d.Path, entity.Path)
+		if !wanted[rel] {
+			stale = append(stale, entity)
+			report.Removed = append(report.Removed, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	for _, entity := range stale {
+		if err := entity.Delete(); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,95 @@
+package filic_test
+
+import (
+	"errors"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/henilmalaviya/filic"
+)
+
+func TestWriteIfChanged(t *testing.T) {
+	cleanup()
+
+	file := filic.NewFile(path.Join(getTempDirPath(), "gen.go"))
+	file.Create()
+
+	written, err := file.WriteIfChanged([]byte("package gen\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !written {
+		t.Error("Expected first write to happen")
+	}
+
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	file.SetTimes(old, old)
+
+	written, err = file.WriteIfChanged([]byte("package gen\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written {
+		t.Error("Expected unchanged content not to be written")
+	}
+
+	modTime, _ := file.ModTime()
+	if !modTime.Equal(old) {
+		t.Errorf("Expected modification time %v, got %v", old, modTime)
+	}
+
+	// same size, different content
+	written, err = file.WriteIfChanged([]byte("package xyz\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !written {
+		t.Error("Expected changed content to be written")
+	}
+
+	content, _ := file.ReadString()
+	if content != "package xyz\n" {
+		t.Errorf("Expected %q, got %q", "package xyz\n", content)
+	}
+
+	cleanup()
+}
+
+func TestApplyTree(t *testing.T) {
+	cleanup()
+
+	dir := createTree(t, map[string]string{
+		"same.go":       "same",
+		"changed.go":    "old",
+		"stale.go":      "stale",
+		"keep/notes.md": "hand written",
+	})
+
+	report, err := dir.ApplyTree(map[string][]byte{
+		"same.go":       []byte("same"),
+		"changed.go":    []byte("new"),
+		"nested/new.go": []byte("new file"),
+	}, filic.ApplyOptions{Prune: true, Ignore: filic.NewIgnoreMatcher("keep/")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectNames(t, []string{"changed.go", "nested/new.go"}, report.Changed)
+	expectNames(t, []string{"same.go"}, report.Unchanged)
+	expectNames(t, []string{"stale.go"}, report.Removed)
+
+	if filic.NewFile(dir.Join("stale.go")).Exists() {
+		t.Error("stale.go should have been removed")
+	}
+	if !filic.NewFile(dir.Join("keep/notes.md")).Exists() {
+		t.Error("Ignored files should not be pruned")
+	}
+
+	_, err = dir.ApplyTree(map[string][]byte{"../escape.go": nil}, filic.ApplyOptions{})
+	if !errors.Is(err, filic.ErrOutsideRoot) {
+		t.Errorf("Expected ErrOutsideRoot, got %v", err)
+	}
+
+	cleanup()
+}